@@ -7,7 +7,10 @@ import (
 	"database/sql"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	_ "github.com/denisenkom/go-mssqldb"
@@ -19,6 +22,9 @@ import (
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/sqlstore"
 )
 
+//time to finish requests and mailing on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func Start(config *model.Service) error {
 
 	dbPostgres, err := newDbPostgres(config)
@@ -79,16 +85,52 @@ func Start(config *model.Service) error {
 		},
 	}
 
-	server := newServer(store_db, config, clt)
+	server, err := newServer(store_db, config, clt)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	recovery, stopRecovery := context.WithCancel(context.Background())
+	defer stopRecovery()
+	go server.recoverSagas(recovery)
 
 	//setup HTTPS server
 	srv := &http.Server{
 		Addr:      config.Spec.Ports.Addr,
 		TLSConfig: configCert,
-		Handler:   server.router,
+		Handler:   server,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.ListenAndServeTLS(fcert, fkey)
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-done:
+		return err
+	case <-stop:
+	}
+
+	//stop accepting requests, then drain mailing queue
+	logger.InfoLogger.Println("shutdown")
+	stopRecovery()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+	if err := server.mailing.Close(ctx); err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
 	}
 
-	return srv.ListenAndServeTLS(fcert, fkey)
+	return nil
 }
 
 //connect to postgres
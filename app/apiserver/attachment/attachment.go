@@ -0,0 +1,118 @@
+package attachment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//default max attachment size
+const defaultMaxSize = 10 << 20
+
+//errors
+var (
+	ErrType = errors.New("attachment type not allowed, pdf, jpeg, png only")
+	ErrSize = errors.New("attachment too large")
+)
+
+//allowed content types, detected by content
+var allowedTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+//Service checks, scans and stores attachments
+type Service struct {
+	storage Storage
+	scanner Scanner
+	maxSize int64
+}
+
+//New service from config, nil if local storage has no dir
+func New(config *model.Service) (*Service, error) {
+
+	if c := config.Spec.Attachments; (c.Storage == "local" || c.Storage == "") && c.Dir == "" {
+		return nil, nil
+	}
+
+	storage, err := NewStorage(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithBackends(storage, NewScanner(config), config.Spec.Attachments.MaxSize), nil
+}
+
+//NewWithBackends service with given storage and scanner
+func NewWithBackends(storage Storage, scanner Scanner, maxSize int64) *Service {
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	return &Service{
+		storage: storage,
+		scanner: scanner,
+		maxSize: maxSize,
+	}
+}
+
+//MaxSize of attachment, bytes
+func (s *Service) MaxSize() int64 {
+	return s.maxSize
+}
+
+//Save checks type and size, scans and stores file, returns attachment without owner
+func (s *Service) Save(ctx context.Context, fileName string, data []byte) (*model.Attachment, error) {
+
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrSize
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, ErrType
+	}
+
+	if err := s.scanner.Scan(ctx, data); err != nil {
+		return nil, err
+	}
+
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Put(ctx, id, contentType, data); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return &model.Attachment{
+		Id:          id,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Sha256:      hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+//Load file of attachment
+func (s *Service) Load(ctx context.Context, a *model.Attachment) ([]byte, error) {
+	return s.storage.Get(ctx, a.Id)
+}
+
+//random attachment id
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,78 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+//storage in memory
+type memStorage map[string][]byte
+
+func (m memStorage) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func (m memStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	return m[key], nil
+}
+
+//scanner with fixed verdict
+type verdict struct {
+	err error
+}
+
+func (v verdict) Scan(ctx context.Context, data []byte) error {
+	return v.err
+}
+
+func TestSave(t *testing.T) {
+
+	pdf := []byte("%PDF-1.4\n%test document\n")
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)
+	errClamd := errors.New("clamd: unavailable")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		maxSize int64
+		scan    error
+		err     error
+		typ     string
+	}{
+		{"pdf", pdf, 0, nil, nil, "application/pdf"},
+		{"png", png, 0, nil, nil, "image/png"},
+		{"max size", pdf, int64(len(pdf)), nil, nil, "application/pdf"},
+		{"too large", pdf, int64(len(pdf)) - 1, nil, ErrSize, ""},
+		{"html", []byte("<html><body>x</body></html>"), 0, nil, ErrType, ""},
+		{"script", []byte("#!/bin/sh\necho\n"), 0, nil, ErrType, ""},
+		{"pdf extension of text", []byte("plain text"), 0, nil, ErrType, ""},
+		{"infected", pdf, 0, ErrInfected, ErrInfected, ""},
+		{"scanner error", pdf, 0, errClamd, errClamd, ""},
+	}
+
+	for _, tt := range tests {
+		storage := memStorage{}
+		s := NewWithBackends(storage, verdict{tt.scan}, tt.maxSize)
+
+		a, err := s.Save(context.Background(), "../dir/file.pdf", tt.data)
+		if err != tt.err {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err != nil {
+			if len(storage) != 0 {
+				t.Errorf("%s: rejected file stored", tt.name)
+			}
+			continue
+		}
+		if a.ContentType != tt.typ || a.FileName != "file.pdf" || a.Size != int64(len(tt.data)) || a.Sha256 != sha256Hex(tt.data) {
+			t.Errorf("%s: attachment %+v", tt.name, a)
+		}
+		if data, _ := s.Load(context.Background(), a); !bytes.Equal(data, tt.data) {
+			t.Errorf("%s: loaded %q", tt.name, data)
+		}
+	}
+}
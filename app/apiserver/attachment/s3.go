@@ -0,0 +1,144 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//S3Storage s3 compatible object storage (minio), path-style urls, signature v4
+type S3Storage struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+//NewS3Storage storage, endpoint with scheme (http://localhost:9000)
+func NewS3Storage(endpoint string, region string, bucket string, accessKey string, secretKey string) *S3Storage {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Storage{
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+//Put object
+func (s *S3Storage) Put(ctx context.Context, key string, contentType string, data []byte) error {
+
+	req, err := s.request(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	_, err = s.do(req)
+	return err
+}
+
+//Get object
+func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.do(req)
+}
+
+func (s *S3Storage) do(req *http.Request) ([]byte, error) {
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s3 %s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+//signed request
+func (s *S3Storage) request(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+
+	path := "/" + s.bucket + "/" + url.PathEscape(key)
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		method,
+		req.URL.EscapedPath(),
+		"",
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	signature := hex.EncodeToString(hmacSha256(signingKey(s.secretKey, date, s.region, "s3"), toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+
+	return req, nil
+}
+
+//signature v4 key of date, region and service
+func signingKey(secret string, date string, region string, service string) []byte {
+	key := hmacSha256([]byte("AWS4"+secret), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, service)
+	return hmacSha256(key, "aws4_request")
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,91 @@
+package attachment
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSigningKey(t *testing.T) {
+
+	//aws signature v4 documentation example
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	if got := hex.EncodeToString(key); got != "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d" {
+		t.Errorf("signing key %s", got)
+	}
+}
+
+var authorization = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([^,]+), Signature=([0-9a-f]{64})$`)
+
+//s3 stand-in verifying signature of request as received
+func s3StandIn(t *testing.T, secret string) *httptest.Server {
+
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		body, _ := ioutil.ReadAll(r.Body)
+		m := authorization.FindStringSubmatch(r.Header.Get("Authorization"))
+		if m == nil || m[1] != "access" || m[3] != "ru-central-1" || m[4] != "host;x-amz-content-sha256;x-amz-date" {
+			t.Errorf("authorization %q", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		amzDate := r.Header.Get("X-Amz-Date")
+		if !strings.HasPrefix(amzDate, m[2]) || r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			t.Errorf("date %s, payload hash %s", amzDate, r.Header.Get("X-Amz-Content-Sha256"))
+		}
+
+		canonical := r.Method + "\n" + r.URL.EscapedPath() + "\n\n" +
+			"host:" + r.Host + "\n" +
+			"x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256") + "\n" +
+			"x-amz-date:" + amzDate + "\n\n" +
+			m[4] + "\n" + r.Header.Get("X-Amz-Content-Sha256")
+		toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + m[2] + "/" + m[3] + "/s3/aws4_request\n" + sha256Hex([]byte(canonical))
+		if want := hex.EncodeToString(hmacSha256(signingKey(secret, m[2], m[3], "s3"), toSign)); m[5] != want {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestS3Storage(t *testing.T) {
+
+	srv := s3StandIn(t, "secret")
+	s := NewS3Storage(srv.URL+"/", "ru-central-1", "bucket", "access", "secret")
+
+	if err := s.Put(context.Background(), "a b.pdf", "application/pdf", []byte("pdf")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Get(context.Background(), "a b.pdf")
+	if err != nil || string(data) != "pdf" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if _, err := s.Get(context.Background(), "missing"); err == nil {
+		t.Error("missing object: no error")
+	}
+
+	//wrong secret is refused by stand-in
+	wrong := NewS3Storage(srv.URL, "ru-central-1", "bucket", "access", "other")
+	if _, err := wrong.Get(context.Background(), "a b.pdf"); err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("wrong secret: error %v, want 403", err)
+	}
+}
@@ -0,0 +1,95 @@
+package attachment
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//errors
+var (
+	ErrInfected = errors.New("attachment infected")
+)
+
+//Scanner virus scan hook
+type Scanner interface {
+	Scan(ctx context.Context, data []byte) error
+}
+
+//NewScanner from config, clamd if address is set
+func NewScanner(config *model.Service) Scanner {
+	if config.Spec.Attachments.ClamdAddr != "" {
+		return &ClamdScanner{Addr: config.Spec.Attachments.ClamdAddr}
+	}
+	return NopScanner{}
+}
+
+//NopScanner accepts everything
+type NopScanner struct{}
+
+//Scan nop
+func (NopScanner) Scan(ctx context.Context, data []byte) error {
+	return nil
+}
+
+//ClamdScanner clamav daemon, INSTREAM command over tcp
+type ClamdScanner struct {
+	Addr string
+}
+
+//Scan data, ErrInfected if clamd found a signature
+func (c *ClamdScanner) Scan(ctx context.Context, data []byte) error {
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	size := make([]byte, 4)
+	for off := 0; off < len(data); off += 1 << 16 {
+		end := off + 1<<16
+		if end > len(data) {
+			end = len(data)
+		}
+		binary.BigEndian.PutUint32(size, uint32(end-off))
+		if _, err := conn.Write(size); err != nil {
+			return err
+		}
+		if _, err := conn.Write(data[off:end]); err != nil {
+			return err
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return err
+	}
+
+	reply, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+
+	result := strings.TrimRight(string(reply), "\x00\n")
+	switch {
+	case strings.HasSuffix(result, "OK"):
+		return nil
+	case strings.HasSuffix(result, "FOUND"):
+		return ErrInfected
+	}
+	return errors.New("clamd: " + result)
+}
@@ -0,0 +1,79 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+//clamd stand-in, reads INSTREAM chunks and answers with reply
+func clamd(t *testing.T, reply string, got *bytes.Buffer) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		cmd := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+			return
+		}
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(got, conn, int64(n)); err != nil {
+				return
+			}
+		}
+		conn.Write([]byte(reply))
+	}()
+
+	return l.Addr().String()
+}
+
+func TestClamdScan(t *testing.T) {
+
+	//chunks of 64 KB and the rest
+	data := bytes.Repeat([]byte("x"), 1<<16+10)
+
+	tests := []struct {
+		reply string
+		err   error
+		ok    bool
+	}{
+		{"stream: OK\x00", nil, true},
+		{"stream: OK\n", nil, true},
+		{"stream: Eicar-Test-Signature FOUND\x00", ErrInfected, false},
+		{"INSTREAM size limit exceeded. ERROR\x00", nil, false},
+	}
+
+	for _, tt := range tests {
+		var got bytes.Buffer
+		c := &ClamdScanner{Addr: clamd(t, tt.reply, &got)}
+
+		err := c.Scan(context.Background(), data)
+		if (err == nil) != tt.ok || (tt.err != nil && err != tt.err) {
+			t.Errorf("%q: error %v", tt.reply, err)
+		}
+		if !bytes.Equal(got.Bytes(), data) {
+			t.Errorf("%q: clamd got %d bytes, want %d", tt.reply, got.Len(), len(data))
+		}
+	}
+}
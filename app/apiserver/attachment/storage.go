@@ -0,0 +1,58 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//errors
+var (
+	ErrStorage = errors.New("unknown attachment storage")
+	ErrDir     = errors.New("attachment dir is required for local storage")
+)
+
+//Storage attachment backend
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+//NewStorage from config
+func NewStorage(config *model.Service) (Storage, error) {
+	c := config.Spec.Attachments
+	switch c.Storage {
+	case "local", "":
+		//empty dir would write to working directory
+		if c.Dir == "" {
+			return nil, ErrDir
+		}
+		return &LocalStorage{Dir: c.Dir}, nil
+	case "s3":
+		return NewS3Storage(c.S3Endpoint, c.S3Region, c.S3Bucket, c.S3AccessKey, c.S3SecretKey), nil
+	}
+	return nil, ErrStorage
+}
+
+//LocalStorage files in dir
+type LocalStorage struct {
+	Dir string
+}
+
+//Put write file
+func (s *LocalStorage) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	f := filepath.Join(s.Dir, filepath.Base(key))
+	if err := os.MkdirAll(s.Dir, 0750); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f, data, 0640)
+}
+
+//Get read file
+func (s *LocalStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(s.Dir, filepath.Base(key)))
+}
@@ -0,0 +1,35 @@
+package attachment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+func TestNewStorage(t *testing.T) {
+
+	config := &model.Service{}
+	if _, err := NewStorage(config); err != ErrDir {
+		t.Errorf("local without dir: error %v, want ErrDir", err)
+	}
+
+	config.Spec.Attachments.Storage = "ftp"
+	if _, err := NewStorage(config); err != ErrStorage {
+		t.Errorf("unknown storage: error %v, want ErrStorage", err)
+	}
+
+	config.Spec.Attachments.Storage = "local"
+	config.Spec.Attachments.Dir = t.TempDir()
+	storage, err := NewStorage(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), "../a.pdf", "application/pdf", []byte("pdf")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := storage.Get(context.Background(), "a.pdf")
+	if err != nil || string(data) != "pdf" {
+		t.Errorf("got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,132 @@
+package apiserver
+
+import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/attachment"
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//errors
+var (
+	errAttachment         = errors.New("attachment storing error")
+	errAttachmentNotFound = errors.New("attachment not found")
+	errAttachmentFile     = errors.New("multipart field file required")
+)
+
+//check attachments of booking exist and belong to user
+func (s *server) checkAttachments(ids []string, userId uint64) error {
+	for _, id := range ids {
+		a, err := s.store.Data().QueryAttachmentPostgres(id)
+		if err == store.ErrRecordNotFound || (err == nil && a.UserId != userId) {
+			return errAttachmentNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//handle attachment upload, multipart field file
+func (s *server) handleUploadAttachment() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		//body is limited by middleware to file size with multipart overhead
+		file, header, err := r.FormFile("file")
+		if errors.Is(err, errBodyTooLarge) {
+			s.bodyError(w, r, err)
+			return
+		}
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, errAttachmentFile)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		defer file.Close()
+
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		a, err := s.attachments.Save(r.Context(), header.Filename, data)
+		switch err {
+		case nil:
+		case attachment.ErrSize:
+			s.error(w, r, http.StatusRequestEntityTooLarge, err)
+			logger.ErrorLogger.Println(err)
+			return
+		case attachment.ErrType:
+			s.error(w, r, http.StatusUnsupportedMediaType, err)
+			logger.ErrorLogger.Println(err)
+			return
+		case attachment.ErrInfected:
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			logger.ErrorLogger.Println(err, header.Filename)
+			return
+		default:
+			s.error(w, r, http.StatusInternalServerError, errAttachment)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		a.UserId = userId(r)
+		if err := s.store.Data().QueryInsertAttachmentPostgres(*a); err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, a)
+		logger.InfoLogger.Println("attachment stored " + a.Id)
+
+	}
+
+}
+
+//handle attachment download, owner only
+func (s *server) handleAttachment() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id := mux.Vars(r)["attachment_id"]
+
+		a, err := s.store.Data().QueryAttachmentPostgres(id)
+		if err == store.ErrRecordNotFound || (err == nil && a.UserId != userId(r)) {
+			s.error(w, r, http.StatusNotFound, errAttachmentNotFound)
+			logger.ErrorLogger.Println(errAttachmentNotFound, id)
+			return
+		}
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		data, err := s.attachments.Load(r.Context(), a)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errAttachment)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		w.Header().Set("Content-Type", a.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+
+	}
+
+}
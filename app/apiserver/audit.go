@@ -0,0 +1,226 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//audit query limits
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+//errors
+var (
+	errAuditFilter = errors.New("audit filter: user_id number, from and to RFC 3339, limit 1-1000")
+)
+
+//audit record of request, filled by auth middleware and handlers
+type auditEntry struct {
+	userId      uint64
+	tokenHash   string
+	integration string //webhook caller
+}
+
+//status of response
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+//Flush of streamed response
+func (w *auditWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+//hash of request body without credentials, empty for empty body
+//password fields are removed before hashing, so hash can't be brute forced
+func payloadHash(body []byte) string {
+
+	if len(body) == 0 {
+		return ""
+	}
+
+	//json keys are matched case insensitive by decoder
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) == nil {
+		n := len(fields)
+		for k := range fields {
+			if strings.EqualFold(k, "password") {
+				delete(fields, k)
+			}
+		}
+		if len(fields) != n {
+			body, _ = json.Marshal(fields)
+		}
+	}
+
+	return hashHex(body)
+}
+
+//set user and token of audited request
+func auditUser(r *http.Request, userId uint64, token string) {
+	if e, ok := r.Context().Value(ctxKeyAudit).(*auditEntry); ok {
+		e.userId = userId
+		e.tokenHash = hashHex([]byte(token))
+	}
+}
+
+//set webhook integration of audited request
+func auditIntegration(r *http.Request, name string) {
+	if e, ok := r.Context().Value(ctxKeyAudit).(*auditEntry); ok {
+		e.integration = name
+	}
+}
+
+//Middleware audit of data-changing requests, before auth and webhook middleware
+func (s *server) middleWareAudit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		//uploads are not buffered, file is hashed while handler reads it
+		var body []byte
+		var hash func() string
+		if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+			h := sha256.New()
+			tee := io.TeeReader(r.Body, h)
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{tee, r.Body}
+			hash = func() string {
+				io.Copy(ioutil.Discard, tee)
+				return hex.EncodeToString(h.Sum(nil))
+			}
+		} else {
+			var err error
+			body, err = ioutil.ReadAll(r.Body)
+			if err != nil {
+				s.bodyError(w, r, err)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			hash = func() string {
+				return payloadHash(body)
+			}
+		}
+
+		//request id of route or json body
+		requestId := mux.Vars(r)["request_id"]
+		if requestId == "" {
+			var payload struct {
+				RequestId string `json:"request_id"`
+			}
+			if json.Unmarshal(body, &payload) == nil {
+				requestId = payload.RequestId
+			}
+		}
+
+		entry := &auditEntry{}
+		aw := &auditWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(aw, r.WithContext(context.WithValue(r.Context(), ctxKeyAudit, entry)))
+
+		e := model.AuditEvent{
+			UserId:      entry.userId,
+			Action:      model.AuditRequest,
+			TokenHash:   entry.tokenHash,
+			Route:       r.Method + " " + r.URL.Path,
+			RequestId:   requestId,
+			ClientIP:    clientIP(r),
+			Outcome:     model.AuditOk,
+			PayloadHash: hash(),
+			Details:     "status=" + strconv.Itoa(aw.status),
+		}
+		if entry.integration != "" {
+			e.Details += " integration=" + entry.integration
+		}
+		if aw.status >= http.StatusBadRequest {
+			e.Outcome = model.AuditError
+		}
+		if err := s.store.Data().QueryInsertAuditPostgres(e); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+
+	})
+}
+
+//handle audit trail, ?user_id=&request_id=&from=&to=&limit=
+func (s *server) handleAudit() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		q := r.URL.Query()
+		filter := model.AuditFilter{
+			RequestId: q.Get("request_id"),
+			Limit:     auditDefaultLimit,
+		}
+
+		var err error
+		if v := q.Get("user_id"); v != "" {
+			var id uint64
+			id, err = strconv.ParseUint(v, 10, 64)
+			filter.UserId = &id
+		}
+		if v := q.Get("from"); v != "" && err == nil {
+			filter.From, err = time.Parse(time.RFC3339, v)
+		}
+		if v := q.Get("to"); v != "" && err == nil {
+			filter.To, err = time.Parse(time.RFC3339, v)
+		}
+		if v := q.Get("limit"); v != "" && err == nil {
+			filter.Limit, err = strconv.Atoi(v)
+			if err == nil && (filter.Limit < 1 || filter.Limit > auditMaxLimit) {
+				err = errAuditFilter
+			}
+		}
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, errAuditFilter)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		events, err := s.store.Data().QueryAuditPostgres(filter)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, events)
+
+	}
+
+}
@@ -0,0 +1,47 @@
+package apiserver
+
+import (
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+func TestAuditPayloadHash(t *testing.T) {
+
+	ts := newTestServer(t)
+	admin := ts.store.Users().Seed("admin@example.ru", "password", model.RoleAdmin)
+
+	ts.do(t, "POST", "/authentication", 0, map[string]string{"email": "admin@example.ru", "password": "password"})
+	ts.do(t, "POST", "/authentication", 0, map[string]string{"email": "admin@example.ru", "password": "wrong password"})
+	ts.do(t, "POST", "/auth/admin/users", admin, map[string]string{"email": "new@example.ru", "Password": "password1", "role": model.RoleClient})
+	ts.do(t, "POST", "/auth/admin/users", admin, map[string]string{"email": "new@example.ru", "password": "password2", "role": model.RoleClient})
+
+	var audit []model.AuditEvent
+	for _, e := range ts.store.Records().Audit {
+		if e.Action == model.AuditRequest {
+			audit = append(audit, e)
+		}
+	}
+	if len(audit) != 4 {
+		t.Fatalf("%d audit records, want 4", len(audit))
+	}
+	if audit[0].Outcome != model.AuditOk || audit[1].Outcome != model.AuditError {
+		t.Errorf("authentication: %+v %+v", audit[0], audit[1])
+	}
+
+	//same body except password gives same hash
+	want := map[int]string{
+		0: hashHex([]byte(`{"email":"admin@example.ru"}`)),
+		1: hashHex([]byte(`{"email":"admin@example.ru"}`)),
+		2: hashHex([]byte(`{"email":"new@example.ru","role":"client"}`)),
+		3: hashHex([]byte(`{"email":"new@example.ru","role":"client"}`)),
+	}
+	for i, e := range audit {
+		if e.PayloadHash != want[i] {
+			t.Errorf("%s: payload hash %s, want %s", e.Route, e.PayloadHash, want[i])
+		}
+	}
+	if audit[3].Details != "status=409" || audit[3].Outcome != model.AuditError {
+		t.Errorf("duplicate user: %+v", audit[3])
+	}
+}
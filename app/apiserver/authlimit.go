@@ -0,0 +1,238 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/ratelimit"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//errors
+var (
+	errAuthLocked = errors.New("too many authentication attempts")
+	errUnlock     = errors.New("email or ip required")
+)
+
+//failed authentication limits per ip and per email
+//failures are counted in memory of instance, lockouts are persisted,
+//so they survive restart and users cli can unlock them
+type authLimits struct {
+	ip    *ratelimit.Lockout
+	email *ratelimit.Lockout
+	users store.UserRepository
+}
+
+//limits from config, zero values are defaults
+func newAuthLimits(config *model.Service, users store.UserRepository) *authLimits {
+	c := config.Spec.AuthLimits
+	seconds := func(v int, def int) time.Duration {
+		if v <= 0 {
+			v = def
+		}
+		return time.Duration(v) * time.Second
+	}
+	attempts := func(v int, def int) int {
+		if v <= 0 {
+			return def
+		}
+		return v
+	}
+	window := seconds(c.Window, 900)
+	lockout := seconds(c.Lockout, 60)
+	maxLockout := seconds(c.MaxLockout, 3600)
+
+	return &authLimits{
+		ip:    ratelimit.NewLockout(attempts(c.IpAttempts, 20), window, lockout, maxLockout),
+		email: ratelimit.NewLockout(attempts(c.EmailAttempts, 5), window, lockout, maxLockout),
+		users: users,
+	}
+}
+
+//lockout of kind
+func (l *authLimits) lockout(kind string) *ratelimit.Lockout {
+	if kind == model.LockoutIp {
+		return l.ip
+	}
+	return l.email
+}
+
+//restore persisted lockouts active at now
+func (l *authLimits) restore(now time.Time) {
+	lockouts, err := l.users.ListAuthLockouts(now)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return
+	}
+	for _, lo := range lockouts {
+		l.lockout(lo.Kind).Restore(ratelimit.Lock{Key: lo.Key, Until: lo.Until, Lockouts: lo.Lockouts}, now)
+	}
+}
+
+//drop lockout of memory if it was unlocked by cli
+func (l *authLimits) confirm(kind string, key string, now time.Time) {
+	lockout := l.lockout(kind)
+	if _, ok := lockout.Locked(key, now); !ok {
+		return
+	}
+	_, err := l.users.FindAuthLockout(kind, key)
+	if err == store.ErrRecordNotFound {
+		lockout.Unlock(key, now)
+		return
+	}
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+}
+
+//persist lockout of key
+func (l *authLimits) save(kind string, key string, now time.Time) {
+	lock, ok := l.lockout(kind).Locked(key, now)
+	if !ok {
+		return
+	}
+	if err := l.users.SaveAuthLockout(model.AuthLockout{Kind: kind, Key: key, Until: lock.Until, Lockouts: lock.Lockouts}); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+}
+
+//unlock key of kind in memory and store, false if key was not locked
+func (l *authLimits) unlock(kind string, key string, now time.Time) (bool, error) {
+	locked := l.lockout(kind).Unlock(key, now)
+	deleted, err := l.users.DeleteAuthLockout(kind, key)
+	return locked || deleted, err
+}
+
+//reserve attempt of ip and email, remaining lockout if attempt is not allowed,
+//reserved attempt ends with fail or success, so parallel attempts can't pass limits
+func (l *authLimits) reserve(ip string, email string, now time.Time) time.Duration {
+	l.confirm(model.LockoutIp, ip, now)
+	l.confirm(model.LockoutEmail, email, now)
+	if d := l.ip.Reserve(ip, now); d > 0 {
+		return d
+	}
+	if d := l.email.Reserve(email, now); d > 0 {
+		l.ip.Release(ip)
+		return d
+	}
+	return 0
+}
+
+//failed attempt, lockouts are logged
+//email is counted whether user exists or not, lockout doesn't disclose accounts
+func (l *authLimits) fail(ip string, email string, now time.Time) {
+	if d := l.ip.Fail(ip, now); d > 0 {
+		logger.ErrorLogger.Printf("auth lockout ip=%s for %s, total lockouts %d", ip, d, l.ip.Total())
+		l.save(model.LockoutIp, ip, now)
+	}
+	if d := l.email.Fail(email, now); d > 0 {
+		logger.ErrorLogger.Printf("auth lockout email=%s for %s, total lockouts %d", email, d, l.email.Total())
+		l.save(model.LockoutEmail, email, now)
+	}
+}
+
+//successful attempt resets email, ip keeps counting failures:
+//one valid account must not reset guessing from the same address
+func (l *authLimits) success(ip string, email string) {
+	l.ip.Release(ip)
+	l.email.Release(email)
+	l.email.Reset(email)
+}
+
+//email key
+func authLimitEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+//write lockout response
+func (s *server) authLocked(w http.ResponseWriter, r *http.Request, d time.Duration) {
+	retryAfter(w, d)
+	s.error(w, r, http.StatusTooManyRequests, errAuthLocked)
+}
+
+//handle lockouts list
+func (s *server) handleLockouts() http.HandlerFunc {
+
+	type response struct {
+		Ip            []ratelimit.Lock `json:"ip"`
+		Email         []ratelimit.Lock `json:"email"`
+		IpLockouts    uint64           `json:"ip_lockouts_total"`
+		EmailLockouts uint64           `json:"email_lockouts_total"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		now := time.Now()
+		s.respond(w, r, http.StatusOK, response{
+			Ip:            s.authLimits.ip.Locks(now),
+			Email:         s.authLimits.email.Locks(now),
+			IpLockouts:    s.authLimits.ip.Total(),
+			EmailLockouts: s.authLimits.email.Total(),
+		})
+
+	}
+}
+
+//handle unlock of email and/or ip, users cli unlocks persisted lockouts too
+func (s *server) handleUnlock() http.HandlerFunc {
+
+	type request struct {
+		Email string `json:"email"`
+		Ip    string `json:"ip"`
+	}
+
+	type response struct {
+		Email bool `json:"email_unlocked"`
+		Ip    bool `json:"ip_unlocked"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		var req request
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+		email := authLimitEmail(req.Email)
+		if email == "" && req.Ip == "" {
+			s.error(w, r, http.StatusBadRequest, errUnlock)
+			return
+		}
+
+		now := time.Now()
+		var resp response
+		var err error
+		if email != "" {
+			resp.Email, err = s.authLimits.unlock(model.LockoutEmail, email, now)
+		}
+		if req.Ip != "" && err == nil {
+			resp.Ip, err = s.authLimits.unlock(model.LockoutIp, req.Ip, now)
+		}
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		actor := requestActor(r)
+		e := model.AuditEvent{
+			UserId:   actor.UserId,
+			Action:   model.AuditAuthUnlock,
+			Route:    actor.Route,
+			ClientIP: actor.ClientIP,
+			Outcome:  model.AuditOk,
+			Details:  "email=" + email + " ip=" + req.Ip,
+		}
+		if err := s.store.Data().QueryInsertAuditPostgres(e); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+		logger.InfoLogger.Printf("auth unlock email=%s ip=%s by user %d", email, req.Ip, actor.UserId)
+
+		s.respond(w, r, http.StatusOK, resp)
+
+	}
+}
@@ -0,0 +1,117 @@
+package apiserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+func TestAuthLimits(t *testing.T) {
+
+	ts := newTestServer(t)
+	ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	login := func(email string, password string) int {
+		return ts.do(t, "POST", "/authentication", 0, map[string]string{"email": email, "password": password}).Code
+	}
+
+	//parallel guesses can't pass email limit
+	codes := make([]int, 30)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = login("client@example.ru", "wrong")
+		}(i)
+	}
+	wg.Wait()
+	wrong := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusUnauthorized:
+			wrong++
+		case http.StatusTooManyRequests:
+		default:
+			t.Errorf("guess: code %d", code)
+		}
+	}
+	if wrong != 5 {
+		t.Errorf("%d passwords checked, want 5", wrong)
+	}
+	if code := login("client@example.ru", "password"); code != http.StatusTooManyRequests {
+		t.Errorf("locked email: code %d", code)
+	}
+
+	//unknown email is limited the same way
+	for i := 0; i < 5; i++ {
+		login("nobody@example.ru", "wrong")
+	}
+	if code := login("nobody@example.ru", "wrong"); code != http.StatusTooManyRequests {
+		t.Errorf("unknown email: code %d", code)
+	}
+
+	//admin unlock, successful attempts don't count for ip
+	ts.authLimits.email.Unlock("client@example.ru", time.Now())
+	for i := 0; i < 20; i++ {
+		if code := login("client@example.ru", "password"); code != http.StatusOK {
+			t.Fatalf("login %d: code %d", i, code)
+		}
+	}
+}
+
+func TestAuthLockoutPersisted(t *testing.T) {
+
+	ts := newTestServer(t)
+	ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	login := func(s *server, password string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/authentication", strings.NewReader(`{"email": "client@example.ru", "password": "`+password+`"}`))
+		r.RemoteAddr = "192.0.2.1:1234"
+		s.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		login(ts.server, "wrong")
+	}
+	if _, err := ts.store.User().FindAuthLockout(model.LockoutEmail, "client@example.ru"); err != nil {
+		t.Fatalf("email lockout not persisted: %v", err)
+	}
+
+	//restarted instance keeps lockout
+	restarted, err := newServer(ts.store, ts.config, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range map[string]*server{"running": ts.server, "restarted": restarted} {
+		if code := login(s, "password"); code != http.StatusTooManyRequests {
+			t.Errorf("%s: code %d, want locked", name, code)
+		}
+	}
+
+	//cli unlock reaches running instances
+	var out bytes.Buffer
+	if err := users(ts.store, []string{"unlock", "email", "Client@example.ru"}, nil, &out); err != nil || out.String() != "ok\n" {
+		t.Fatalf("unlock: %q, %v", out.String(), err)
+	}
+	for name, s := range map[string]*server{"running": ts.server, "restarted": restarted} {
+		if code := login(s, "password"); code != http.StatusOK {
+			t.Errorf("%s after unlock: code %d", name, code)
+		}
+	}
+	out.Reset()
+	if err := users(ts.store, []string{"unlock", "email", "client@example.ru"}, nil, &out); err != nil || !strings.Contains(out.String(), "not locked") {
+		t.Errorf("repeated unlock: %q, %v", out.String(), err)
+	}
+	if err := users(ts.store, []string{"unlock", "user", "client@example.ru"}, nil, &out); err == nil {
+		t.Error("unknown unlock kind accepted")
+	}
+}
@@ -0,0 +1,109 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//errors
+var (
+	errBillAction        = errors.New("booking action_type is not bill")
+	errBillNotConfigured = errors.New("bill generation not configured")
+	errBill              = errors.New("bill generation error")
+)
+
+//generate and store bill of booking
+func (s *server) storeBill(data model.DataBooking) ([]byte, error) {
+
+	if s.bill == nil {
+		return nil, errBillNotConfigured
+	}
+
+	pdf, err := s.bill.Bill(data, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Data().QueryInsertBillPostgres(data.RequestId, data.BillNumber, pdf); err != nil {
+		return nil, err
+	}
+
+	return pdf, nil
+}
+
+//handle bill download, generated on first request if missing
+func (s *server) handleBill() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		requestId := mux.Vars(r)["request_id"]
+
+		status, err := s.store.Data().QueryBookingStatusPostgres(requestId)
+		if err == store.ErrRecordNotFound || (err == nil && status.Booking == nil) {
+			s.error(w, r, http.StatusNotFound, errBookingNotFound)
+			logger.ErrorLogger.Println(errBookingNotFound, requestId)
+			return
+		}
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		ok, err := s.canAccessBooking(status.Saga, userId(r))
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+		if !ok {
+			s.error(w, r, http.StatusForbidden, errBookingForbidden)
+			logger.ErrorLogger.Println(errBookingForbidden, requestId, userId(r))
+			return
+		}
+
+		billNumber, pdf, err := s.store.Data().QueryBillPostgres(requestId)
+		if err == store.ErrRecordNotFound {
+
+			if status.Booking.ActionType != "bill" {
+				s.error(w, r, http.StatusBadRequest, errBillAction)
+				logger.ErrorLogger.Println(errBillAction, requestId)
+				return
+			}
+
+			billNumber = status.Booking.BillNumber
+			pdf, err = s.storeBill(*status.Booking)
+			if err == errBillNotConfigured {
+				s.error(w, r, http.StatusServiceUnavailable, err)
+				logger.ErrorLogger.Println(err)
+				return
+			}
+			if err != nil {
+				s.error(w, r, http.StatusInternalServerError, errBill)
+				logger.ErrorLogger.Println(err)
+				return
+			}
+
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"bill_"+billNumber+".pdf\"")
+		w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdf)
+		logger.InfoLogger.Println("bill sent " + requestId)
+
+	}
+
+}
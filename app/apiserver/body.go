@@ -0,0 +1,120 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+)
+
+//default body limit, bytes
+const bodyMaxSize = 1 << 20
+
+//errors
+var (
+	errBodyTooLarge = errors.New("request body too large")
+	errBodyTrailing = errors.New("request body must contain a single json object")
+)
+
+//request body limited by MaxBytesReader, exceeding limit is errBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		err = errBodyTooLarge
+	}
+	return n, err
+}
+
+//route template of request
+func routePath(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if t, err := route.GetPathTemplate(); err == nil {
+			return t
+		}
+	}
+	return r.URL.Path
+}
+
+//body limit and strict decoding of route
+//attachments upload defaults to attachment size with multipart overhead
+func (s *server) bodyRule(r *http.Request) (int64, bool) {
+	c := s.config.Spec.Body
+	path := routePath(r)
+
+	size, strict := c.MaxSize, c.Strict
+	if size <= 0 {
+		size = bodyMaxSize
+	}
+	if path == "/auth/attachments" && s.attachments != nil {
+		size = s.attachments.MaxSize() + 1<<20
+	}
+	for _, route := range c.Routes {
+		if route.Path == path {
+			if route.MaxSize > 0 {
+				size = route.MaxSize
+			}
+			strict = strict || route.Strict
+		}
+	}
+	return size, strict
+}
+
+//Middleware body size limit, before any middleware reading body
+func (s *server) middleWareBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		size, _ := s.bodyRule(r)
+		if r.ContentLength > size {
+			s.error(w, r, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+			logger.ErrorLogger.Println(errBodyTooLarge)
+			return
+		}
+		r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, size), limit: size}
+
+		next.ServeHTTP(w, r)
+
+	})
+}
+
+//decode json body into v, unknown fields are rejected in strict mode, trailing data always
+//empty body is io.EOF
+func (s *server) decode(r *http.Request, v interface{}) error {
+	_, strict := s.bodyRule(r)
+
+	dec := json.NewDecoder(r.Body)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	var trailing json.RawMessage
+	switch err := dec.Decode(&trailing); {
+	case err == io.EOF:
+		return nil
+	case errors.Is(err, errBodyTooLarge):
+		return err
+	}
+	return errBodyTrailing
+}
+
+//write body error: 413 for too large body, 400 otherwise
+func (s *server) bodyError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errBodyTooLarge) {
+		s.error(w, r, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+	} else {
+		s.error(w, r, http.StatusBadRequest, err)
+	}
+	logger.ErrorLogger.Println(err)
+}
@@ -0,0 +1,82 @@
+package apiserver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBodyLimits(t *testing.T) {
+
+	ts := newTestServer(t)
+	admin := ts.store.Users().Seed("admin@example.ru", "password", model.RoleAdmin)
+	client := ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	if err := yaml.Unmarshal([]byte(`
+max_size: 128
+routes:
+  - {path: /authentication, max_size: 64, strict: true}
+`), &ts.config.Spec.Body); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer(ts.store, ts.config, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.server = s
+
+	login := `{"email":"client@example.ru","password":"password"}`
+	role := "/auth/admin/users/" + strconv.FormatUint(client, 10) + "/role"
+	padding := strings.Repeat(" ", 100)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool //no content length, limit is hit while decoding
+		code    int
+	}{
+		{"login", "/authentication", login, false, http.StatusOK},
+		{"trailing space", "/authentication", login + "\n", false, http.StatusOK},
+		{"route size", "/authentication", login + padding, false, http.StatusRequestEntityTooLarge},
+		{"route size chunked", "/authentication", login + padding, true, http.StatusRequestEntityTooLarge},
+		{"unknown field strict", "/authentication", `{"email":"client@example.ru","password":"password","admin":true}`, false, http.StatusBadRequest},
+		{"trailing object", "/authentication", login + `{}`, false, http.StatusBadRequest},
+		{"trailing garbage", "/authentication", login + `x`, false, http.StatusBadRequest},
+		{"unknown field", role, `{"role":"client","admin":true}`, false, http.StatusOK},
+		{"trailing object default", role, `{"role":"client"}{"role":"admin"}`, false, http.StatusBadRequest},
+		{"default size", role, `{"role":"client"}` + padding + padding, false, http.StatusRequestEntityTooLarge},
+		{"default size chunked", role, `{"role":"client"}` + padding + padding, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/json")
+		if tt.chunked {
+			r.ContentLength = -1
+			r.Body = ioutil.NopCloser(strings.NewReader(tt.body))
+		}
+		if tt.path == role {
+			token, _, err := ts.tokens.Issue(admin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s: %d %s, want %d", tt.name, w.Code, w.Body, tt.code)
+		}
+	}
+
+	//rejected role change is not applied
+	if u, err := ts.store.User().FindUserById(client); err != nil || u.Role != model.RoleClient {
+		t.Errorf("role after rejected bodies: %+v %v", u, err)
+	}
+}
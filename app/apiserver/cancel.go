@@ -0,0 +1,128 @@
+package apiserver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/gazcrm"
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//errors
+var (
+	errCancelForbidden = errors.New("booking may be cancelled by originating client or admin only")
+	errCancelState     = errors.New("booking is not active")
+)
+
+//responses
+var (
+	respCancel = "booking cancelled"
+)
+
+//handle booking cancellation, body {"reason": ""} is optional
+func (s *server) handleCancelBooking() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := model.Cancellation{}
+
+		if err := s.decode(r, &req); err != nil && err != io.EOF {
+			s.bodyError(w, r, err)
+			return
+		}
+		req.RequestId = mux.Vars(r)["request_id"]
+		req.UserId = userId(r)
+
+		status, err := s.store.Data().QueryBookingStatusPostgres(req.RequestId)
+		if err == store.ErrRecordNotFound || (err == nil && (status.Booking == nil || status.Saga == nil)) {
+			s.error(w, r, http.StatusNotFound, errBookingNotFound)
+			logger.ErrorLogger.Println(errBookingNotFound, req.RequestId)
+			return
+		}
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		ok, err := s.canAccessBooking(status.Saga, req.UserId)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+		if !ok {
+			s.error(w, r, http.StatusForbidden, errCancelForbidden)
+			logger.ErrorLogger.Println(errCancelForbidden, req.RequestId, req.UserId)
+			return
+		}
+
+		//claim booking, concurrent cancellations fail here
+		ok, err = s.store.Data().QueryCompareSagaStatePostgres(req.RequestId, model.SagaCompleted, model.SagaCancelled)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+		if !ok {
+			s.error(w, r, http.StatusConflict, errCancelState)
+			logger.ErrorLogger.Println(errCancelState, req.RequestId, status.Saga.State)
+			return
+		}
+
+		booking := *status.Booking
+
+		result := newResponseBooking("Ok", "", "", "")
+		result.State = model.SagaCancelled
+
+		resp, err := s.store.Data().QueryCancelMssql(booking, req.Reason)
+		if err != nil || resp != respMssqlOk {
+			logger.ErrorLogger.Println(err, resp)
+			//release claim, booking stays reserved
+			if _, err := s.store.Data().QueryCompareSagaStatePostgres(req.RequestId, model.SagaCancelled, model.SagaCompleted); err != nil {
+				logger.ErrorLogger.Println(err)
+			}
+			if err != nil {
+				s.error(w, r, http.StatusBadGateway, errMssql)
+				return
+			}
+			s.respond(w, r, http.StatusBadRequest, newResponseBooking("Error", resp, "", ""))
+			return
+		}
+		result.ResponseMs = resp
+
+		if err := s.store.Data().QueryInsertSagaStepPostgres(req.RequestId, model.SagaStep{Step: model.SagaStepCancel, Status: model.SagaStepDone}); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+		if err := s.store.Data().QueryInsertCancellationPostgres(req); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+
+		//notify gaz crm with lead event, re-sending the booking would create a duplicate lead
+		respg, err := s.gazCrm(booking.TestMod).Event(r.Context(), req.RequestId, gazcrm.EventCancelled, req.Reason)
+		if err != nil {
+			logger.ErrorLogger.Println(err)
+			result.StatusGazCrm = "Error"
+			result.ResponseGazCrm = gazCrmMessage(respg, err)
+		} else {
+			result.StatusGazCrm = "Ok"
+			result.ResponseGazCrm = respCancel
+		}
+
+		if err := s.store.Data().QueryInsertBookingResultPostgres(req.RequestId, *result); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+
+		s.applyLeadEvent(req.RequestId, model.LeadEvent{Source: model.LeadSourceSite, EventName: model.LeadClosed, TimeEvent: time.Now().Format("2006-01-02T15:04:05")})
+
+		s.respond(w, r, http.StatusOK, result)
+		logger.InfoLogger.Println("booking cancelled " + req.RequestId)
+
+	}
+
+}
@@ -0,0 +1,93 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/teststore"
+)
+
+func TestCancelBooking(t *testing.T) {
+
+	ts := newTestServer(t)
+	owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+	other := ts.store.Users().Seed("other@example.ru", "password", model.RoleClient)
+	interruptedSaga(t, ts, "r1", owner, model.SagaStepStore, model.SagaStepReserve, model.SagaStepDeliver)
+	ts.store.Data().QueryUpdateSagaStatePostgres("r1", model.SagaCompleted)
+
+	tests := []struct {
+		name   string
+		path   string
+		userId uint64
+		code   int
+	}{
+		{"missing booking", "/auth/bookings/missing/cancel", owner, http.StatusNotFound},
+		{"other client", "/auth/bookings/r1/cancel", other, http.StatusForbidden},
+		{"owner", "/auth/bookings/r1/cancel", owner, http.StatusOK},
+		{"repeated", "/auth/bookings/r1/cancel", owner, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		if w := ts.do(t, "POST", tt.path, tt.userId, map[string]string{"reason": "changed mind"}); w.Code != tt.code {
+			t.Errorf("%s: code %d, want %d: %s", tt.name, w.Code, tt.code, w.Body)
+		}
+	}
+
+	if got := sagaState(t, ts, "r1"); got != model.SagaCancelled {
+		t.Errorf("state %s, want cancelled", got)
+	}
+	//cancellation is sent to gaz crm as event, not as a new lead
+	if ts.crm() != 0 {
+		t.Errorf("%d gaz crm lead requests, want none", ts.crm())
+	}
+	if got, want := ts.events(), []string{"r1 booking_cancelled"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("gaz crm events %v, want %v", got, want)
+	}
+}
+
+func TestCancelBookingMssqlFailure(t *testing.T) {
+
+	ts := newTestServer(t)
+	owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+	interruptedSaga(t, ts, "r1", owner, model.SagaStepStore, model.SagaStepReserve, model.SagaStepDeliver)
+	ts.store.Data().QueryUpdateSagaStatePostgres("r1", model.SagaCompleted)
+
+	ts.store.Records().Script(teststore.ProcBookingCancel,
+		teststore.Procedure{Err: errors.New("mssql is down")},
+		teststore.Procedure{Response: "Бронь не найдена"},
+	)
+
+	tests := []struct {
+		name string
+		code int
+	}{
+		{"mssql error", http.StatusBadGateway},
+		{"mssql refused", http.StatusBadRequest},
+		{"retry after release", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := ts.do(t, "POST", "/auth/bookings/r1/cancel", owner, nil)
+		if w.Code != tt.code {
+			t.Errorf("%s: code %d, want %d: %s", tt.name, w.Code, tt.code, w.Body)
+		}
+		if tt.code != http.StatusOK {
+			//claim is released, booking stays reserved
+			if got := sagaState(t, ts, "r1"); got != model.SagaCompleted {
+				t.Errorf("%s: state %s, want completed", tt.name, got)
+			}
+		}
+	}
+
+	if got := sagaState(t, ts, "r1"); got != model.SagaCancelled {
+		t.Errorf("state %s, want cancelled", got)
+	}
+	if n := len(ts.store.Records().Cancellations); n != 1 {
+		t.Errorf("%d cancellations recorded, want 1", n)
+	}
+	if got := ts.events(); len(got) != 1 {
+		t.Errorf("gaz crm events %v, want one", got)
+	}
+}
@@ -0,0 +1,305 @@
+package apiserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//default compression threshold, bytes
+const compressMinSize = 1024
+
+//error of waiting requests if catalog load panicked
+var errCatalogLoad = errors.New("catalog load failed")
+
+//accept-encoding allows gzip, q=0 refuses,
+//explicit gzip coding takes precedence over wildcard in any order
+func acceptsGzip(r *http.Request) bool {
+	var gzip, wildcard *bool
+	for _, e := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(e, ";")
+		accepted := !codingRefused(parts[1:])
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "gzip":
+			gzip = &accepted
+		case "*":
+			wildcard = &accepted
+		}
+	}
+	if gzip != nil {
+		return *gzip
+	}
+	return wildcard != nil && *wildcard
+}
+
+//coding parameters with q=0
+func codingRefused(params []string) bool {
+	for _, p := range params {
+		if q := strings.ToLower(strings.ReplaceAll(p, " ", "")); strings.HasPrefix(q, "q=0") && strings.Trim(q[3:], ".0") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+//compression threshold and level from config
+func (s *server) compressConfig() (int, int) {
+	c := s.config.Spec.Compression
+	min, level := c.MinSize, c.Level
+	if min <= 0 {
+		min = compressMinSize
+	}
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return min, level
+}
+
+//gzip bytes
+func gzipBytes(data []byte, level int) ([]byte, error) {
+	var b bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&b, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+//response writer buffering up to threshold, compresses larger responses
+//responses with Content-Encoding set by handler pass through
+type gzipWriter struct {
+	http.ResponseWriter
+	min         int
+	level       int
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if g.status != 0 {
+		return
+	}
+	g.status = code
+	if g.Header().Get("Content-Encoding") != "" || code == http.StatusNoContent || code == http.StatusNotModified {
+		g.passthrough = true
+		g.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.passthrough {
+		return g.ResponseWriter.Write(p)
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+
+	g.buf = append(g.buf, p...)
+	if len(g.buf) < g.min {
+		return len(p), nil
+	}
+	if err := g.start(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+//write header and buffered data, response is compressed from now on
+func (g *gzipWriter) start() error {
+	h := g.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	g.ResponseWriter.WriteHeader(g.status)
+
+	gz, err := gzip.NewWriterLevel(g.ResponseWriter, g.level)
+	if err != nil {
+		return err
+	}
+	g.gz = gz
+	if _, err := g.gz.Write(g.buf); err != nil {
+		return err
+	}
+	g.buf = nil
+	return nil
+}
+
+//Flush streamed response, compression starts below threshold: size is unknown
+func (g *gzipWriter) Flush() {
+	if g.status == 0 {
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.passthrough {
+		if g.gz == nil {
+			if err := g.start(); err != nil {
+				return
+			}
+		}
+		if err := g.gz.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+//flush buffered response or gzip trailer
+func (g *gzipWriter) close() error {
+	if g.passthrough {
+		return nil
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	if g.status == 0 {
+		return nil
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(g.buf)
+	return err
+}
+
+//Middleware gzip compression negotiated by Accept-Encoding
+func (s *server) middleWareGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if s.config.Spec.Compression.Disabled || r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		min, level := s.compressConfig()
+		g := &gzipWriter{ResponseWriter: w, min: min, level: level}
+		next.ServeHTTP(g, r)
+		g.close()
+
+	})
+}
+
+//cached catalog response, gzip is stored for responses above threshold
+type catalogEntry struct {
+	json    []byte
+	gzip    []byte
+	expires time.Time
+}
+
+//catalog responses by route
+type catalogCache struct {
+	mu      sync.Mutex
+	entries map[string]catalogEntry
+	calls   map[string]*catalogCall
+}
+
+//load of catalog entry in progress, waited by concurrent requests of route
+type catalogCall struct {
+	done  chan struct{}
+	entry catalogEntry
+	err   error
+}
+
+func newCatalogCache() *catalogCache {
+	return &catalogCache{
+		entries: make(map[string]catalogEntry),
+		calls:   make(map[string]*catalogCall),
+	}
+}
+
+//cached entry of key, expired entry is loaded once for concurrent requests,
+//load errors are shared by waiting requests and not cached
+func (c *catalogCache) load(key string, now time.Time, load func() (catalogEntry, error)) (catalogEntry, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && !now.After(e.expires) {
+		c.mu.Unlock()
+		return e, nil
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.entry, call.err
+	}
+	call := &catalogCall{done: make(chan struct{}), err: errCatalogLoad}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	//waiters are released on panic of load too
+	defer func() {
+		c.mu.Lock()
+		if call.err == nil {
+			c.entries[key] = call.entry
+		}
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.entry, call.err = load()
+	return call.entry, call.err
+}
+
+//respond catalog data, cached with pre-compressed copy for spec.compression.catalog_ttl
+//without ttl data is loaded on every request and compressed by middleware
+func (s *server) respondCatalog(w http.ResponseWriter, r *http.Request, load func() (interface{}, error)) error {
+
+	ttl := time.Duration(s.config.Spec.Compression.CatalogTtl) * time.Second
+	if ttl <= 0 {
+		data, err := load()
+		if err != nil {
+			return err
+		}
+		s.respond(w, r, http.StatusOK, data)
+		return nil
+	}
+
+	now := time.Now()
+	e, err := s.catalog.load(routePath(r), now, func() (catalogEntry, error) {
+		data, err := load()
+		if err != nil {
+			return catalogEntry{}, err
+		}
+		var b bytes.Buffer
+		if err := json.NewEncoder(&b).Encode(data); err != nil {
+			return catalogEntry{}, err
+		}
+		e := catalogEntry{json: b.Bytes(), expires: now.Add(ttl)}
+		if min, level := s.compressConfig(); !s.config.Spec.Compression.Disabled && len(e.json) >= min {
+			if e.gzip, err = gzipBytes(e.json, level); err != nil {
+				return catalogEntry{}, err
+			}
+		}
+		return e, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if e.gzip != nil && acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		w.Write(e.gzip)
+		return nil
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(e.json)
+	return nil
+}
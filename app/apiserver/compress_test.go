@@ -0,0 +1,158 @@
+package apiserver
+
+import (
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+
+	tests := map[string]bool{
+		"":                        false,
+		"gzip":                    true,
+		"GZIP, deflate":           true,
+		"deflate, br":             false,
+		"gzip;q=0":                false,
+		"gzip; q=0.000":           false,
+		"gzip;q=0.5":              true,
+		"gzip;Q=0":                false,
+		"*":                       true,
+		"*;q=0":                   false,
+		"*, gzip;q=0":             false,
+		"gzip;q=0, *":             false,
+		"*;q=0, gzip":             true,
+		"gzip, *;q=0":             true,
+		"deflate, *;q=0.1":        true,
+		"identity, *;q=0, br;q=1": false,
+	}
+
+	for header, want := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := acceptsGzip(r); got != want {
+			t.Errorf("%q: %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestGzipFlush(t *testing.T) {
+
+	s := &server{config: &model.Service{}}
+
+	//chunk below threshold is sent compressed on flush
+	w := httptest.NewRecorder()
+	flushed := 0
+	h := s.middleWareGzip(http.HandlerFunc(func(gw http.ResponseWriter, r *http.Request) {
+		f, ok := gw.(http.Flusher)
+		if !ok {
+			t.Fatal("gzip writer is not http.Flusher")
+		}
+		gw.Write([]byte("first "))
+		f.Flush()
+		flushed = w.Body.Len()
+		gw.Write([]byte("second"))
+	}))
+
+	r := httptest.NewRequest("GET", "/auth/events", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(w, r)
+
+	if !w.Flushed || flushed == 0 {
+		t.Fatalf("flushed %v, %d bytes", w.Flushed, flushed)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding %q", got)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadAll(gz); err != nil || string(b) != "first second" {
+		t.Errorf("body %q %v", b, err)
+	}
+
+	//passthrough response is flushed as is
+	h = s.middleWareGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "identity")
+		w.Write([]byte("raw"))
+		w.(http.Flusher).Flush()
+	}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if !w.Flushed || w.Body.String() != "raw" {
+		t.Errorf("passthrough flushed %v body %q", w.Flushed, w.Body)
+	}
+}
+
+func TestCatalogCacheLoad(t *testing.T) {
+
+	c := newCatalogCache()
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	var loads int32
+	release := make(chan struct{})
+	load := func() (catalogEntry, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return catalogEntry{json: []byte("{}"), expires: now.Add(time.Minute)}, nil
+	}
+
+	//concurrent requests of expired route share one load
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e, err := c.load("/auth/getsprav", now, load); err != nil || string(e.json) != "{}" {
+				t.Errorf("entry %q %v", e.json, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if loads != 1 {
+		t.Errorf("%d loads, want 1", loads)
+	}
+
+	//expired entry is reloaded
+	if _, err := c.load("/auth/getsprav", now.Add(2*time.Minute), load); err != nil || loads != 2 {
+		t.Errorf("reload: %d loads %v", loads, err)
+	}
+
+	//error is not cached
+	errLoad := errors.New("load")
+	fail := func() (catalogEntry, error) {
+		return catalogEntry{}, errLoad
+	}
+	if _, err := c.load("/auth/getcolorsdata", now, fail); err != errLoad {
+		t.Errorf("load error %v", err)
+	}
+	if _, err := c.load("/auth/getcolorsdata", now, load); err != nil || loads != 3 {
+		t.Errorf("after error: %d loads %v", loads, err)
+	}
+
+	//waiters are released if load panics
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic of load is not propagated")
+			}
+		}()
+		c.load("/auth/getpacketsdata", now, func() (catalogEntry, error) {
+			panic("load")
+		})
+	}()
+	if _, err := c.load("/auth/getpacketsdata", now, load); err != nil || loads != 4 {
+		t.Errorf("after panic: %d loads %v", loads, err)
+	}
+}
@@ -0,0 +1,116 @@
+package apiserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//defaults of cors group
+var (
+	corsMethods = []string{http.MethodGet, http.MethodPost}
+	corsHeaders = []string{"Authorization", "Content-Type"}
+)
+
+//first cors group of path prefix, nil if path has no group
+func (s *server) corsGroup(path string) *model.CorsGroup {
+	groups := s.config.Spec.Cors.Groups
+	for i := range groups {
+		if strings.HasPrefix(path, groups[i].Prefix) {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+//cors headers of request, true if preflight was answered
+//preflight is handled before routing: routes are registered without OPTIONS and need no token
+func (s *server) cors(w http.ResponseWriter, r *http.Request) bool {
+
+	g := s.corsGroup(r.URL.Path)
+	if g == nil {
+		return false
+	}
+	//response of group depends on origin, also without one: cached response must not be shared
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	//wildcard can't be combined with credentials, origins must be listed
+	wildcard := corsContains(g.Origins, "*") && !g.Credentials
+	if !wildcard && !corsContains(g.Origins, origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+			logger.ErrorLogger.Println("cors origin not allowed: " + origin)
+			return true
+		}
+		return false
+	}
+
+	if wildcard {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if g.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if !preflight {
+		if len(g.Expose) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(g.Expose, ", "))
+		}
+		return false
+	}
+
+	methods, headers := g.Methods, g.Headers
+	if len(methods) == 0 {
+		methods = corsMethods
+	}
+	if len(headers) == 0 {
+		headers = corsHeaders
+	}
+
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	if !corsContains(methods, r.Header.Get("Access-Control-Request-Method")) {
+		w.WriteHeader(http.StatusForbidden)
+		logger.ErrorLogger.Println("cors method not allowed: " + r.Header.Get("Access-Control-Request-Method"))
+		return true
+	}
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if h = strings.TrimSpace(h); h != "" && !corsContains(headers, h) {
+			w.WriteHeader(http.StatusForbidden)
+			logger.ErrorLogger.Println("cors header not allowed: " + h)
+			return true
+		}
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	if g.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(g.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return true
+}
+
+//case insensitive list lookup
+func corsContains(list []string, v string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, v) {
+			return true
+		}
+	}
+	return false
+}
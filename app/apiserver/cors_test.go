@@ -0,0 +1,122 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCors(t *testing.T) {
+
+	ts := newTestServer(t)
+
+	if err := yaml.Unmarshal([]byte(`
+groups:
+  - prefix: /authentication
+    origins: [https://dealer.example.ru]
+    methods: [POST]
+    headers: [Content-Type]
+    max_age: 600
+  - prefix: /auth/bookings
+    origins: ["*"]
+    expose: [Retry-After]
+  - prefix: /auth/get
+    origins: ["*", https://dealer.example.ru]
+    credentials: true
+`), &ts.config.Spec.Cors); err != nil {
+		t.Fatal(err)
+	}
+
+	const dealer, other = "https://dealer.example.ru", "https://other.example.ru"
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		origin      string
+		reqMethod   string //preflight
+		reqHeaders  string
+		code        int
+		allow       string //Access-Control-Allow-Origin
+		credentials bool
+		vary        bool
+	}{
+		{"preflight", "OPTIONS", "/authentication", dealer, "POST", "content-type", http.StatusNoContent, dealer, false, true},
+		{"preflight origin case", "OPTIONS", "/authentication", "HTTPS://DEALER.example.ru", "POST", "", http.StatusNoContent, "HTTPS://DEALER.example.ru", false, true},
+		{"preflight disallowed origin", "OPTIONS", "/authentication", other, "POST", "", http.StatusForbidden, "", false, true},
+		{"preflight disallowed method", "OPTIONS", "/authentication", dealer, "GET", "", http.StatusForbidden, dealer, false, true},
+		{"preflight disallowed header", "OPTIONS", "/authentication", dealer, "POST", "Content-Type, Authorization", http.StatusForbidden, dealer, false, true},
+		{"disallowed origin", "GET", "/auth/getsprav", other, "", "", http.StatusUnauthorized, "", false, true},
+		{"no origin", "GET", "/auth/getsprav", "", "", "", http.StatusUnauthorized, "", false, true},
+		{"wildcard", "GET", "/auth/bookings/req-1", other, "", "", http.StatusUnauthorized, "*", false, true},
+		{"preflight wildcard", "OPTIONS", "/auth/bookings/req-1", other, "GET", "Authorization", http.StatusNoContent, "*", false, true},
+		{"credentials listed origin", "GET", "/auth/getsprav", dealer, "", "", http.StatusUnauthorized, dealer, true, true},
+		{"credentials preflight", "OPTIONS", "/auth/getsprav", dealer, "GET", "Authorization", http.StatusNoContent, dealer, true, true},
+		{"credentials wildcard ignored", "OPTIONS", "/auth/getsprav", other, "GET", "", http.StatusForbidden, "", false, true},
+		{"path without group", "OPTIONS", "/webhook/requeststatus", dealer, "POST", "", http.StatusMethodNotAllowed, "", false, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if tt.reqMethod != "" {
+			r.Header.Set("Access-Control-Request-Method", tt.reqMethod)
+		}
+		if tt.reqHeaders != "" {
+			r.Header.Set("Access-Control-Request-Headers", tt.reqHeaders)
+		}
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, r)
+
+		if w.Code != tt.code {
+			t.Errorf("%s: %d, want %d", tt.name, w.Code, tt.code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allow {
+			t.Errorf("%s: Allow-Origin %q, want %q", tt.name, got, tt.allow)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.credentials {
+			t.Errorf("%s: Allow-Credentials %v, want %v", tt.name, got, tt.credentials)
+		}
+		if got := corsContains(w.Header().Values("Vary"), "Origin"); got != tt.vary {
+			t.Errorf("%s: Vary %q", tt.name, w.Header().Values("Vary"))
+		}
+	}
+
+	//preflight headers and exposed headers of actual request
+	r := httptest.NewRequest("OPTIONS", "/authentication", nil)
+	r.Header.Set("Origin", dealer)
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, r)
+	if h := w.Header(); h.Get("Access-Control-Allow-Methods") != "POST" || h.Get("Access-Control-Allow-Headers") != "Content-Type" || h.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight headers %v", h)
+	}
+
+	r = httptest.NewRequest("GET", "/auth/bookings/req-1", nil)
+	r.Header.Set("Origin", other)
+	w = httptest.NewRecorder()
+	ts.ServeHTTP(w, r)
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "Retry-After" {
+		t.Errorf("expose headers %q", got)
+	}
+}
+
+func TestCorsGroup(t *testing.T) {
+
+	s := &server{config: &model.Service{}}
+	s.config.Spec.Cors.Groups = []model.CorsGroup{{Prefix: "/auth/get"}, {Prefix: "/auth"}}
+
+	for path, want := range map[string]string{"/auth/getsprav": "/auth/get", "/auth/bookings/1": "/auth", "/authentication": "/auth", "/webhook/requeststatus": ""} {
+		got := ""
+		if g := s.corsGroup(path); g != nil {
+			got = g.Prefix
+		}
+		if got != want {
+			t.Errorf("%s: group %q, want %q", path, got, want)
+		}
+	}
+}
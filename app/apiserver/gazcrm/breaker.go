@@ -0,0 +1,64 @@
+package gazcrm
+
+import (
+	"sync"
+	"time"
+)
+
+//circuit breaker
+//opens after consecutive failures, lets one trial call through after cooldown
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+	trial     bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+//allow call
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trial = true
+
+	return true
+}
+
+//call ended without result, trial slot is freed
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+//record call result
+func (b *breaker) done(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
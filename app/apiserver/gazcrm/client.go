@@ -0,0 +1,210 @@
+package gazcrm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//defaults for zero config values
+const (
+	defaultTimeout         = 5 * time.Second
+	defaultRetries         = 2
+	defaultBackoff         = 200 * time.Millisecond
+	defaultBreakerFailures = 5
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+//Client gaz crm api
+type Client struct {
+	http     *http.Client
+	url      string
+	events   string //lead events, empty - not sent
+	user     string
+	password string
+	test     bool
+	timeout  time.Duration
+	retries  int
+	backoff  time.Duration
+	breaker  *breaker
+}
+
+//New client of test or prod gaz crm, http client transport is shared between calls
+//url of the selected crm is required, prod bookings never fall back to test crm
+func New(httpClient *http.Client, test bool, config *model.Service) (*Client, error) {
+
+	c := &Client{
+		http:     httpClient,
+		url:      config.Spec.Client.UrlGazCrm,
+		events:   config.Spec.Client.UrlGazCrmEvents,
+		user:     config.Spec.Client.UserGazCrm,
+		password: config.Spec.Client.PasswordGazCrm,
+		test:     test,
+		timeout:  time.Duration(config.Spec.Client.GazCrmTimeout) * time.Second,
+		retries:  defaultRetries,
+		backoff:  time.Duration(config.Spec.Client.GazCrmBackoff) * time.Millisecond,
+	}
+	if test {
+		c.url = config.Spec.Client.UrlGazCrmTest
+		c.events = config.Spec.Client.UrlGazCrmEventsTest
+		c.user = config.Spec.Client.UserGazCrmTest
+		c.password = config.Spec.Client.PasswordGazCrmTest
+	}
+	if c.url == "" {
+		return nil, ErrConfig
+	}
+	if c.timeout == 0 {
+		c.timeout = defaultTimeout
+	}
+	if r := config.Spec.Client.GazCrmRetries; r != nil && *r >= 0 {
+		c.retries = *r
+	}
+	if c.backoff == 0 {
+		c.backoff = defaultBackoff
+	}
+
+	failures := config.Spec.Client.GazCrmBreakerFailures
+	if failures == 0 {
+		failures = defaultBreakerFailures
+	}
+	cooldown := time.Duration(config.Spec.Client.GazCrmBreakerCooldown) * time.Second
+	if cooldown == 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	c.breaker = newBreaker(failures, cooldown)
+
+	return c, nil
+}
+
+//Booking send booking to gaz crm
+func (c *Client) Booking(ctx context.Context, data model.DataBooking) (*model.ResponseGazCrm, error) {
+	return c.Send(ctx, BookingRequest(data, c.test))
+}
+
+//Form send form to gaz crm
+func (c *Client) Form(ctx context.Context, data model.DataForms) (*model.ResponseGazCrm, error) {
+	return c.Send(ctx, FormRequest(data, c.test))
+}
+
+//Event send event of existing lead to gaz crm events url, ErrEventsConfig if url is not configured
+func (c *Client) Event(ctx context.Context, requestId string, event string, comment string) (*model.ResponseGazCrm, error) {
+
+	if c.events == "" {
+		return nil, ErrEventsConfig
+	}
+	return c.send(ctx, c.events, EventRequest(requestId, event, comment, c.test))
+}
+
+//Send request to gaz crm api
+func (c *Client) Send(ctx context.Context, data *model.DataGazCrm) (*model.ResponseGazCrm, error) {
+	return c.send(ctx, c.url, data)
+}
+
+//5xx and timeouts are retried with jittered exponential backoff
+func (c *Client) send(ctx context.Context, url string, data interface{}) (*model.ResponseGazCrm, error) {
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	var resp *model.ResponseGazCrm
+	for attempt := 0; ; attempt++ {
+
+		resp, err = c.do(ctx, url, body)
+		if err == nil || attempt >= c.retries || !retryable(err) {
+			break
+		}
+		logger.WarningLogger.Printf("gazcrm attempt %d: %v", attempt+1, err)
+
+		select {
+		case <-time.After(c.delay(attempt)):
+		case <-ctx.Done():
+			c.breaker.release()
+			return nil, ctx.Err()
+		}
+	}
+
+	//caller gone, no verdict on crm
+	if ctx.Err() != nil {
+		c.breaker.release()
+		return resp, err
+	}
+
+	//rejected data is not a crm failure
+	_, rejected := err.(*ResponseError)
+	c.breaker.done(err == nil || rejected)
+
+	return resp, err
+}
+
+//one attempt
+func (c *Client) do(ctx context.Context, url string, body []byte) (*model.ResponseGazCrm, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	bodyBytesResp, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytesResp)}
+	}
+
+	response := &model.ResponseGazCrm{}
+	if err := json.Unmarshal(bodyBytesResp, response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != "OK" {
+		return response, &ResponseError{Status: response.Status, Message: response.Message}
+	}
+
+	return response, nil
+}
+
+//backoff delay of attempt, base * 2^attempt plus jitter up to base
+func (c *Client) delay(attempt int) time.Duration {
+	return c.backoff<<uint(attempt) + time.Duration(rand.Int63n(int64(c.backoff)))
+}
+
+//5xx and timeouts
+func retryable(err error) bool {
+	switch e := err.(type) {
+	case *StatusError:
+		return e.StatusCode >= 500
+	case net.Error:
+		return e.Timeout()
+	}
+	return err == context.DeadlineExceeded
+}
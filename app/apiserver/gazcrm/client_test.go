@@ -0,0 +1,241 @@
+package gazcrm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//gaz crm stand-in answering with statuses in order, last status repeats
+type standIn struct {
+	*httptest.Server
+	calls    int32
+	statuses []int
+	mu       sync.Mutex
+	message  string
+	delay    time.Duration
+	last     model.DataGazCrm
+}
+
+func newStandIn(t *testing.T, statuses ...int) *standIn {
+	s := &standIn{statuses: statuses, message: "OK"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&s.calls, 1)) - 1
+		if n >= len(s.statuses) {
+			n = len(s.statuses) - 1
+		}
+		var last model.DataGazCrm
+		json.NewDecoder(r.Body).Decode(&last)
+		s.mu.Lock()
+		s.last = last
+		delay, message := s.delay, s.message
+		s.mu.Unlock()
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(s.statuses[n])
+		json.NewEncoder(w).Encode(model.ResponseGazCrm{Status: message, Message: "message"})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *standIn) count() int {
+	return int(atomic.LoadInt32(&s.calls))
+}
+
+func (s *standIn) set(message string, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.message, s.delay = message, delay
+}
+
+func (s *standIn) request() model.DataGazCrm {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.last
+}
+
+//client of stand-in, retries nil - default
+func testClient(url string, retries *int, failures int) *Client {
+	config := &model.Service{}
+	config.Spec.Client.UrlGazCrm = url
+	config.Spec.Client.UrlGazCrmTest = url
+	config.Spec.Client.GazCrmRetries = retries
+	config.Spec.Client.GazCrmBackoff = 1
+	config.Spec.Client.GazCrmBreakerFailures = failures
+	config.Spec.Client.GazCrmBreakerCooldown = 3600
+	c, err := New(http.DefaultClient, false, config)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func intp(v int) *int {
+	return &v
+}
+
+func TestSendRetries(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		retries  *int
+		statuses []int
+		calls    int
+		ok       bool
+	}{
+		{"success", nil, []int{200}, 1, true},
+		{"5xx retried", nil, []int{502, 503, 200}, 3, true},
+		{"retries exhausted", nil, []int{500}, 1 + defaultRetries, false},
+		{"retries disabled", intp(0), []int{500, 200}, 1, false},
+		{"retries configured", intp(4), []int{500}, 5, false},
+		{"negative is default", intp(-1), []int{500}, 1 + defaultRetries, false},
+		{"4xx not retried", nil, []int{400, 200}, 1, false},
+	}
+
+	for _, tt := range tests {
+		srv := newStandIn(t, tt.statuses...)
+		c := testClient(srv.URL, tt.retries, 100)
+
+		_, err := c.Form(context.Background(), model.DataForms{RequestId: "r1"})
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v", tt.name, err)
+		}
+		if srv.count() != tt.calls {
+			t.Errorf("%s: %d calls, want %d", tt.name, srv.count(), tt.calls)
+		}
+	}
+}
+
+func TestSendRejectedIsNotFailure(t *testing.T) {
+
+	srv := newStandIn(t, 200)
+	srv.set("ERROR", 0)
+	c := testClient(srv.URL, intp(0), 1)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Form(context.Background(), model.DataForms{})
+		if _, ok := err.(*ResponseError); !ok {
+			t.Fatalf("call %d: error %v, want ResponseError", i, err)
+		}
+	}
+	if srv.count() != 3 {
+		t.Errorf("%d calls, breaker must stay closed", srv.count())
+	}
+}
+
+func TestBreakerOpens(t *testing.T) {
+
+	srv := newStandIn(t, 500)
+	c := testClient(srv.URL, intp(0), 2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Form(context.Background(), model.DataForms{}); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if _, err := c.Form(context.Background(), model.DataForms{}); err != ErrCircuitOpen {
+		t.Errorf("error %v, want ErrCircuitOpen", err)
+	}
+	if srv.count() != 2 {
+		t.Errorf("%d calls, open breaker must not call crm", srv.count())
+	}
+}
+
+func TestCancelledCallerIsNotFailure(t *testing.T) {
+
+	srv := newStandIn(t, 200)
+	srv.set("OK", time.Second)
+	c := testClient(srv.URL, intp(0), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.Form(ctx, model.DataForms{}); err == nil {
+		t.Fatal("expected error of cancelled call")
+	}
+
+	srv.set("OK", 0)
+	if _, err := c.Form(context.Background(), model.DataForms{}); err != nil {
+		t.Errorf("breaker opened by cancelled caller: %v", err)
+	}
+}
+
+func TestTestModeTagging(t *testing.T) {
+
+	srv := newStandIn(t, 200)
+	config := &model.Service{}
+	config.Spec.Client.UrlGazCrm = "http://127.0.0.1:1"
+	config.Spec.Client.UrlGazCrmTest = srv.URL
+	c, err := New(http.DefaultClient, true, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := model.DataForms{Clientid: "ga", MetricsType: "yandex"}
+	if _, err := c.Form(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	req := srv.request()
+	if !req.Data.TestMod.TestMod || req.Data.ClientID.ClientID != "" || req.Data.MetricsType.MetricsType != "" {
+		t.Errorf("test request not tagged: %+v", req.Data)
+	}
+}
+
+func TestUrlRequired(t *testing.T) {
+
+	config := &model.Service{}
+	config.Spec.Client.UrlGazCrmTest = "http://crm-test"
+
+	if _, err := New(http.DefaultClient, false, config); err != ErrConfig {
+		t.Errorf("prod without url: error %v, want ErrConfig", err)
+	}
+	if _, err := New(http.DefaultClient, true, config); err != nil {
+		t.Errorf("test: %v", err)
+	}
+
+	config.Spec.Client.UrlGazCrm = "http://crm"
+	config.Spec.Client.UrlGazCrmTest = ""
+	if _, err := New(http.DefaultClient, true, config); err != ErrConfig {
+		t.Errorf("test without url: error %v, want ErrConfig", err)
+	}
+}
+
+func TestEvent(t *testing.T) {
+
+	c := testClient("http://127.0.0.1:1", intp(0), 100)
+	if _, err := c.Event(context.Background(), "r1", EventPaid, ""); err != ErrEventsConfig {
+		t.Errorf("error %v, want ErrEventsConfig", err)
+	}
+
+	var got model.DataGazCrmEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			t.Errorf("path %s, want events url", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(model.ResponseGazCrm{Status: "OK"})
+	}))
+	defer srv.Close()
+
+	c.events = srv.URL + "/events"
+	if _, err := c.Event(context.Background(), "r1", EventCancelled, "changed mind"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Data.RequestId.RequestId != "r1" || got.Data.EventName.EventName != EventCancelled || got.Data.Commentary.Commentary != "changed mind" || got.Data.TimeRequest.TimeRequest == "" {
+		t.Errorf("event %+v", got.Data)
+	}
+}
@@ -0,0 +1,32 @@
+package gazcrm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrCircuitOpen  = errors.New("gazcrm circuit open")
+	ErrConfig       = errors.New("gazcrm url is not configured")
+	ErrEventsConfig = errors.New("gazcrm events url is not configured")
+)
+
+//non-2xx http status of gaz crm api
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("gazcrm http status %d: %s", e.StatusCode, e.Body)
+}
+
+//gaz crm api responded with status other than OK
+type ResponseError struct {
+	Status  string
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("gazcrm status %s: %s", e.Status, e.Message)
+}
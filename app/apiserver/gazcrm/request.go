@@ -0,0 +1,95 @@
+package gazcrm
+
+import (
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//gaz crm request from booking
+func BookingRequest(data model.DataBooking, test bool) *model.DataGazCrm {
+	return newRequest(model.DataForms{
+		TimeRequest:      data.TimeRequest,
+		RequestId:        data.RequestId,
+		SubdivisionsId:   data.SubdivisionsId,
+		SubdivisionsName: data.SubdivisionsName,
+		FormName:         data.FormName,
+		FormId:           data.FormId,
+		HostName:         data.HostName,
+		Division:         data.Division,
+		Area:             data.Area,
+		BrandName:        data.BrandName,
+		CarModel:         data.CarModel,
+		Clientid:         data.Clientid,
+		MetricsType:      data.MetricsType,
+		СlientIP:         data.СlientIP,
+		TypeClient:       data.TypeClient,
+		CompanyName:      data.CompanyName,
+		Name:             data.Name,
+		Email:            data.Email,
+		PhoneNumber:      data.PhoneNumber,
+		Comment:          data.Comment,
+		Consentmailing:   data.Consentmailing,
+	}, test)
+}
+
+//gaz crm request from form
+func FormRequest(data model.DataForms, test bool) *model.DataGazCrm {
+	return newRequest(data, test)
+}
+
+//test requests are tagged and carry no metrics ids, so they stay out of analytics
+func newRequest(data model.DataForms, test bool) *model.DataGazCrm {
+
+	b := &model.DataGazCrm{}
+
+	b.Data.TimeRequest.TimeRequest = data.TimeRequest
+	b.Data.RequestId.RequestId = data.RequestId
+	b.Data.SubdivisionsId.SubdivisionsId = data.SubdivisionsId
+	b.Data.SubdivisionsName.SubdivisionsName = data.SubdivisionsName
+	b.Data.FormName.FormName = data.FormName
+	b.Data.FormId.FormId = data.FormId
+	b.Data.HostName.HostName = data.HostName
+	b.Data.Division.Division = data.Division
+	b.Data.Area.Area = data.Area
+	b.Data.BrandName.BrandName = data.BrandName
+	b.Data.CarModel.CarModel = data.CarModel
+	b.Data.ClientID.ClientID = data.Clientid
+	b.Data.MetricsType.MetricsType = data.MetricsType
+	b.Data.СlientIP.СlientIP = data.СlientIP
+	b.Data.TypeClient.TypeClient = data.TypeClient
+	b.Data.CompanyName.CompanyName = data.CompanyName
+	b.Data.СlientName.СlientName = data.Name
+	b.Data.ClientEmail.ClientEmail = data.Email
+	b.Data.ClientPhoneNumber.ClientPhoneNumber = data.PhoneNumber
+	b.Data.Commentary.Commentary = data.Comment
+	b.Data.AgreementMailing.AgreementMailing = data.Consentmailing
+	b.Data.TestMod.TestMod = test
+
+	if test {
+		b.Data.ClientID.ClientID = ""
+		b.Data.MetricsType.MetricsType = ""
+	}
+
+	return b
+}
+
+//lead events
+const (
+	EventPaid      = "booking_paid"
+	EventCancelled = "booking_cancelled"
+)
+
+//gaz crm event of existing lead
+func EventRequest(requestId string, event string, comment string, test bool) *model.DataGazCrmEvent {
+
+	b := &model.DataGazCrmEvent{}
+
+	b.Data.TimeRequest.TimeRequest = time.Now().Format("2006-01-02T15:04:05")
+	b.Data.EventName.EventName = event
+	b.Data.RequestId.RequestId = requestId
+	b.Data.Commentary.Commentary = comment
+	b.Data.TestMod.TestMod = test
+
+	return b
+}
@@ -0,0 +1,505 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/teststore"
+)
+
+var errStore = errors.New("store unavailable")
+
+//route request and expected status
+type routeCase struct {
+	name   string
+	method string
+	path   string
+	userId uint64
+	body   interface{}
+	code   int
+}
+
+func (ts *testServer) check(t *testing.T, tests []routeCase) {
+	for _, tt := range tests {
+		if w := ts.do(t, tt.method, tt.path, tt.userId, tt.body); w.Code != tt.code {
+			t.Errorf("%s: %s %s code %d, want %d: %s", tt.name, tt.method, tt.path, w.Code, tt.code, w.Body)
+		}
+	}
+}
+
+//raw body request, body is sent as is
+func (ts *testServer) raw(t *testing.T, method string, path string, userId uint64, contentType string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	if userId != 0 {
+		token, _, err := ts.tokens.Issue(userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, r)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("response %q: %v", w.Body, err)
+	}
+}
+
+//valid booking of personal client
+func validBooking(requestId string, actionType string) model.DataBooking {
+	return model.DataBooking{
+		RequestId:           requestId,
+		ActionType:          actionType,
+		UniqModCode:         1,
+		Modification:        "A21R33",
+		ModFamily:           "ГАЗель NEXT",
+		ModBodyType:         "борт",
+		ModEngine:           "cummins",
+		ModBase:             "base",
+		ModTuning:           "none",
+		Vin:                 "X96A21R33K2000001",
+		PriceWithNds:        2500000,
+		TypeClient:          "personal",
+		DeliveryAddressCode: "52",
+		Hid:                 "h1",
+		Surname:             "Иванов",
+		Name:                "Иван",
+		DateOfBirth:         "1980-01-02",
+		PassportSer:         "4506",
+		PassportNumber:      "123456",
+		Email:               "client@example.ru",
+		PhoneNumber:         "+79991234567",
+		BillNumber:          "B-1",
+		Consentmailing:      "yes",
+	}
+}
+
+func validForm(requestId string) model.DataForms {
+	return model.DataForms{
+		RequestId:        requestId,
+		SubdivisionsId:   "s1",
+		SubdivisionsName: "dealer",
+		FormName:         "call",
+		FormId:           "f1",
+		HostName:         "example.ru",
+		BrandName:        "gaz",
+		CarModel:         "next",
+		Clientid:         "ga",
+		MetricsType:      "yandex",
+		Name:             "Иван",
+		Email:            "client@example.ru",
+		PhoneNumber:      "+79991234567",
+		UrlMod:           "https://example.ru",
+		Modification:     "A21R33",
+		ModFamily:        "ГАЗель NEXT",
+		ModBodyType:      "борт",
+		ModEngine:        "cummins",
+		ModBase:          "base",
+		ModTuning:        "none",
+		Vin:              "X96A21R33K2000001",
+		PriceWithNds:     2500000,
+		ActionType:       "form",
+	}
+}
+
+//booking of user through the api
+func (ts *testServer) book(t *testing.T, userId uint64, requestId string, actionType string) {
+	if w := ts.do(t, "POST", "/auth/requestbooking", userId, validBooking(requestId, actionType)); w.Code != http.StatusOK {
+		t.Fatalf("booking %s: code %d: %s", requestId, w.Code, w.Body)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+
+	ts := newTestServer(t)
+	ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	w := ts.do(t, "POST", "/authentication", 0, map[string]string{"email": "client@example.ru", "password": "password"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("code %d: %s", w.Code, w.Body)
+	}
+	var token model.Token_exp
+	decodeBody(t, w, &token)
+
+	//issued token opens private routes
+	r := httptest.NewRequest("GET", "/auth/getdatastocks", nil)
+	r.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	ts.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("issued token: code %d: %s", w.Code, w.Body)
+	}
+
+	ts.check(t, []routeCase{
+		{"wrong password", "POST", "/authentication", 0, map[string]string{"email": "client@example.ru", "password": "wrong"}, http.StatusUnauthorized},
+		{"unknown email", "POST", "/authentication", 0, map[string]string{"email": "nobody@example.ru", "password": "password"}, http.StatusUnauthorized},
+		{"get", "GET", "/authentication", 0, nil, http.StatusMethodNotAllowed},
+	})
+
+	if w := ts.raw(t, "POST", "/authentication", 0, "application/json", `{"email": `); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed json: code %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+
+	ts := newTestServer(t)
+	id := ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	ts.check(t, []routeCase{
+		{"no token", "GET", "/auth/getdatastocks", 0, nil, http.StatusUnauthorized},
+		{"unknown user", "GET", "/auth/getdatastocks", id + 100, nil, http.StatusUnauthorized},
+		{"client", "GET", "/auth/getdatastocks", id, nil, http.StatusOK},
+	})
+
+	if w := ts.raw(t, "GET", "/auth/getdatastocks", 0, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: code %d", w.Code)
+	}
+	r := httptest.NewRequest("GET", "/auth/getdatastocks", nil)
+	r.Header.Set("Authorization", "Bearer not.a.token")
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token: code %d", w.Code)
+	}
+
+	//disabled user loses access with valid token
+	if err := ts.store.User().UpdateUserDisabled(id, true); err != nil {
+		t.Fatal(err)
+	}
+	ts.check(t, []routeCase{{"disabled", "GET", "/auth/getdatastocks", id, nil, http.StatusUnauthorized}})
+}
+
+func TestRequestBooking(t *testing.T) {
+
+	ts := newTestServer(t)
+	id := ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	ts.book(t, id, "r1", "form")
+	if got := sagaState(t, ts, "r1"); got != model.SagaCompleted {
+		t.Errorf("state %s, want completed", got)
+	}
+	if ts.crm() != 1 {
+		t.Errorf("%d gaz crm requests, want 1", ts.crm())
+	}
+
+	invalid := validBooking("r2", "form")
+	invalid.Vin = "bad"
+	ts.check(t, []routeCase{
+		{"repeated request id", "POST", "/auth/requestbooking", id, validBooking("r1", "form"), http.StatusConflict},
+		{"invalid", "POST", "/auth/requestbooking", id, invalid, http.StatusBadRequest},
+		{"unknown attachment", "POST", "/auth/requestbooking", id, func() model.DataBooking {
+			b := validBooking("r3", "form")
+			b.Attachments = []string{"missing"}
+			return b
+		}(), http.StatusBadRequest},
+	})
+
+	//mssql rejects, reservation is not made
+	ts.store.Records().Script(teststore.ProcBooking, teststore.Procedure{Response: "vin reserved"})
+	ts.check(t, []routeCase{{"mssql rejects", "POST", "/auth/requestbooking", id, validBooking("r4", "form"), http.StatusBadRequest}})
+	if got := sagaState(t, ts, "r4"); got != model.SagaFailed {
+		t.Errorf("rejected: state %s, want failed", got)
+	}
+
+	//mssql call error, possible reservation is compensated
+	ts.store.Records().Script(teststore.ProcBooking, teststore.Procedure{Err: errStore})
+	ts.check(t, []routeCase{{"mssql error", "POST", "/auth/requestbooking", id, validBooking("r5", "form"), http.StatusBadGateway}})
+	if got := sagaState(t, ts, "r5"); got != model.SagaCompensated {
+		t.Errorf("mssql error: state %s, want compensated", got)
+	}
+
+	ts.store.Records().Errors["QueryInsertBookingPostgres"] = errStore
+	ts.check(t, []routeCase{{"postgres error", "POST", "/auth/requestbooking", id, validBooking("r6", "form"), http.StatusInternalServerError}})
+}
+
+func TestRequestForm(t *testing.T) {
+
+	ts := newTestServer(t)
+	id := ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	invalid := validForm("f2")
+	invalid.PhoneNumber = "123"
+	ts.check(t, []routeCase{
+		{"valid", "POST", "/auth/requestform", id, validForm("f1"), http.StatusOK},
+		{"invalid", "POST", "/auth/requestform", id, invalid, http.StatusBadRequest},
+		{"booking action", "POST", "/auth/requestform", id, func() model.DataForms {
+			f := validForm("f3")
+			f.ActionType = "bill"
+			return f
+		}(), http.StatusBadRequest},
+	})
+	if n := len(ts.store.Records().Forms); n != 1 {
+		t.Errorf("%d forms stored, want 1", n)
+	}
+}
+
+func TestGazCrmRoutes(t *testing.T) {
+
+	ts := newTestServer(t)
+	id := ts.store.Users().Seed("crm@example.ru", "password", model.RoleClient)
+
+	body := map[string]interface{}{"Data": map[string]interface{}{}}
+	routes := map[string]string{
+		"/auth/requestleadget":  "QueryInsertLeadGetPostgres",
+		"/auth/requestworklist": "QueryInsertWorkListsPostgres",
+		"/auth/requeststatus":   "QueryInsertStatusesPostgres",
+	}
+
+	for path, method := range routes {
+		ts.check(t, []routeCase{
+			{"valid", "POST", path, id, body, http.StatusOK},
+			{"no token", "POST", path, 0, body, http.StatusUnauthorized},
+		})
+		if w := ts.raw(t, "POST", path, id, "application/json", `{"Data": `); w.Code != http.StatusBadRequest {
+			t.Errorf("malformed json: %s code %d", path, w.Code)
+		}
+		ts.store.Records().Errors[method] = errStore
+		ts.check(t, []routeCase{{"store error", "POST", path, id, body, http.StatusBadRequest}})
+	}
+}
+
+func TestBookingRoutes(t *testing.T) {
+
+	ts := newTestServer(t)
+	owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+	other := ts.store.Users().Seed("other@example.ru", "password", model.RoleClient)
+	admin := ts.store.Users().Seed("admin@example.ru", "password", model.RoleAdmin)
+
+	ts.book(t, owner, "form", "form")
+	ts.book(t, owner, "acquiring", "acquiring")
+	ts.book(t, owner, "bill", "bill")
+
+	ts.check(t, []routeCase{
+		//status
+		{"status of owner", "GET", "/auth/bookings/form", owner, nil, http.StatusOK},
+		{"status of admin", "GET", "/auth/bookings/form", admin, nil, http.StatusOK},
+		{"status of other", "GET", "/auth/bookings/form", other, nil, http.StatusForbidden},
+		{"status missing", "GET", "/auth/bookings/missing", owner, nil, http.StatusNotFound},
+		//payment
+		{"payment of other", "POST", "/auth/bookings/acquiring/payment", other, nil, http.StatusForbidden},
+		{"payment of form", "POST", "/auth/bookings/form/payment", owner, nil, http.StatusBadRequest},
+		{"payment missing", "POST", "/auth/bookings/missing/payment", owner, nil, http.StatusNotFound},
+		//bill, generator is not configured
+		{"bill of other", "GET", "/auth/bookings/bill/bill", other, nil, http.StatusForbidden},
+		{"bill of form", "GET", "/auth/bookings/form/bill", owner, nil, http.StatusBadRequest},
+		{"bill missing", "GET", "/auth/bookings/missing/bill", owner, nil, http.StatusNotFound},
+		{"bill not configured", "GET", "/auth/bookings/bill/bill", owner, nil, http.StatusServiceUnavailable},
+	})
+
+	//repeated payment request gets the pending session
+	var first, second model.Payment
+	w := ts.do(t, "POST", "/auth/bookings/acquiring/payment", owner, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("payment: code %d: %s", w.Code, w.Body)
+	}
+	decodeBody(t, w, &first)
+	decodeBody(t, ts.do(t, "POST", "/auth/bookings/acquiring/payment", owner, nil), &second)
+	if first.PaymentId == "" || first.PaymentId != second.PaymentId || first.Amount != 250000000 {
+		t.Errorf("payments %+v %+v", first, second)
+	}
+
+	//stored bill is sent to owner
+	if err := ts.store.Data().QueryInsertBillPostgres("bill", "B-1", []byte("%PDF")); err != nil {
+		t.Fatal(err)
+	}
+	w = ts.do(t, "GET", "/auth/bookings/bill/bill", owner, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "%PDF" || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("bill: code %d %q", w.Code, w.Body)
+	}
+
+	//cancelled booking can't be paid
+	ts.check(t, []routeCase{{"cancel", "POST", "/auth/bookings/acquiring/cancel", owner, nil, http.StatusOK}})
+	ts.store.Records().Script(teststore.ProcBooking)
+	ts.book(t, owner, "acquiring2", "acquiring")
+	ts.check(t, []routeCase{{"cancel", "POST", "/auth/bookings/acquiring2/cancel", admin, nil, http.StatusOK}})
+	ts.check(t, []routeCase{{"payment of cancelled", "POST", "/auth/bookings/acquiring2/payment", owner, nil, http.StatusConflict}})
+
+	ts.store.Records().Errors["QueryBookingStatusPostgres"] = errStore
+	ts.check(t, []routeCase{
+		{"status store error", "GET", "/auth/bookings/form", owner, nil, http.StatusInternalServerError},
+		{"payment store error", "POST", "/auth/bookings/form/payment", owner, nil, http.StatusInternalServerError},
+		{"bill store error", "GET", "/auth/bookings/bill/bill", owner, nil, http.StatusInternalServerError},
+		{"cancel store error", "POST", "/auth/bookings/form/cancel", owner, nil, http.StatusInternalServerError},
+	})
+}
+
+func TestAttachmentRoutes(t *testing.T) {
+
+	ts := newTestServer(t)
+	owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+	other := ts.store.Users().Seed("other@example.ru", "password", model.RoleClient)
+
+	var last bytes.Buffer
+	upload := func(name string, data string) *httptest.ResponseRecorder {
+		last.Reset()
+		mw := multipart.NewWriter(&last)
+		f, _ := mw.CreateFormFile("file", name)
+		f.Write([]byte(data))
+		mw.Close()
+		return ts.raw(t, "POST", "/auth/attachments", owner, mw.FormDataContentType(), last.String())
+	}
+
+	w := upload("scan.pdf", "%PDF-1.4\n%test document\n")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: code %d: %s", w.Code, w.Body)
+	}
+	var a model.Attachment
+	decodeBody(t, w, &a)
+
+	//upload is hashed as streamed by handler
+	audit := ts.store.Records().Audit
+	if e := audit[len(audit)-1]; e.Route != "POST /auth/attachments" || e.PayloadHash != hashHex(last.Bytes()) {
+		t.Errorf("upload audit %+v, want hash of multipart body", e)
+	}
+
+	if w := upload("script.sh", "#!/bin/sh\necho\n"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("not allowed type: code %d", w.Code)
+	}
+	if w := ts.raw(t, "POST", "/auth/attachments", owner, "application/json", "{}"); w.Code != http.StatusBadRequest {
+		t.Errorf("no file: code %d", w.Code)
+	}
+
+	ts.check(t, []routeCase{
+		{"owner", "GET", "/auth/attachments/" + a.Id, owner, nil, http.StatusOK},
+		{"other", "GET", "/auth/attachments/" + a.Id, other, nil, http.StatusNotFound},
+		{"missing", "GET", "/auth/attachments/missing", owner, nil, http.StatusNotFound},
+	})
+
+	//attachment of other user can't be linked to booking
+	b := validBooking("r1", "form")
+	b.Attachments = []string{a.Id}
+	ts.check(t, []routeCase{{"foreign attachment", "POST", "/auth/requestbooking", other, b, http.StatusBadRequest}})
+	ts.check(t, []routeCase{{"own attachment", "POST", "/auth/requestbooking", owner, b, http.StatusOK}})
+	if got := ts.store.Records().BookingAttachments("r1"); len(got) != 1 || got[0] != a.Id {
+		t.Errorf("booking attachments %v", got)
+	}
+}
+
+func TestAttachmentsNotConfigured(t *testing.T) {
+
+	ts := newTestServer(t)
+	ts.config.Spec.Attachments.Dir = ""
+	s, err := newServer(ts.store, ts.config, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.server = s
+	owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+
+	ts.check(t, []routeCase{
+		{"upload", "POST", "/auth/attachments", owner, nil, http.StatusNotFound},
+		{"download", "GET", "/auth/attachments/a1", owner, nil, http.StatusNotFound},
+		{"booking", "POST", "/auth/requestbooking", owner, validBooking("r1", "form"), http.StatusOK},
+	})
+}
+
+func TestCatalogRoutes(t *testing.T) {
+
+	ts := newTestServer(t)
+	id := ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+	ts.store.Records().Stocks = []model.DataStocks{{VIN: "X96A21R33K2000001"}}
+
+	w := ts.do(t, "GET", "/auth/getdatastocks", id, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "X96A21R33K2000001") {
+		t.Errorf("stocks: code %d %s", w.Code, w.Body)
+	}
+
+	routes := map[string]string{
+		"/auth/getdatastocks":       "QueryStocksMssql",
+		"/auth/getbasicmodelsprice": "QueryBasicModelsPriceMssql",
+		"/auth/getoptionsprice":     "QueryOptionsPriceMssql",
+		"/auth/getgeneralprice":     "QueryGeneralPriceMssql",
+		"/auth/getsprav":            "QuerySprav",
+		"/auth/getoptionsdata":      "QueryOptionsData",
+		"/auth/getoptionsdatasprav": "QueryOptionsDataSprav",
+		"/auth/getpacketsdata":      "QueryPacketsData",
+		"/auth/getcolorsdata":       "QueryColorsData",
+	}
+	for path, method := range routes {
+		ts.check(t, []routeCase{
+			{"ok", "GET", path, id, nil, http.StatusOK},
+			{"no token", "GET", path, 0, nil, http.StatusUnauthorized},
+			{"post", "POST", path, id, nil, http.StatusMethodNotAllowed},
+		})
+		ts.store.Records().Errors[method] = errStore
+		ts.check(t, []routeCase{{"mssql error", "GET", path, id, nil, http.StatusBadRequest}})
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+
+	ts := newTestServer(t)
+	admin := ts.store.Users().Seed("admin@example.ru", "password", model.RoleAdmin)
+	client := ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+
+	//clients are refused on every admin route
+	for _, r := range [][2]string{
+		{"GET", "/auth/admin/users"}, {"POST", "/auth/admin/users"}, {"GET", "/auth/admin/users/1"},
+		{"DELETE", "/auth/admin/users/1"}, {"POST", "/auth/admin/users/1/disable"}, {"POST", "/auth/admin/users/1/enable"},
+		{"POST", "/auth/admin/users/1/password"}, {"POST", "/auth/admin/users/1/role"}, {"GET", "/auth/admin/audit"},
+		{"GET", "/auth/admin/lockouts"}, {"POST", "/auth/admin/lockouts/unlock"}, {"GET", "/auth/admin/ratelimits"},
+	} {
+		ts.check(t, []routeCase{{"client", r[0], r[1], client, nil, http.StatusForbidden}})
+	}
+
+	w := ts.do(t, "POST", "/auth/admin/users", admin, model.User{Email: "new@example.ru", Password: "password1", Role: model.RoleClient})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: code %d: %s", w.Code, w.Body)
+	}
+	var u model.User
+	decodeBody(t, w, &u)
+	path := "/auth/admin/users/" + strings.TrimSpace(string(mustJSON(t, u.Id)))
+
+	ts.check(t, []routeCase{
+		{"list", "GET", "/auth/admin/users", admin, nil, http.StatusOK},
+		{"get", "GET", path, admin, nil, http.StatusOK},
+		{"get missing", "GET", "/auth/admin/users/999", admin, nil, http.StatusNotFound},
+		{"bad id", "GET", "/auth/admin/users/abc", admin, nil, http.StatusBadRequest},
+		{"create duplicate", "POST", "/auth/admin/users", admin, model.User{Email: "new@example.ru", Password: "password1", Role: model.RoleClient}, http.StatusConflict},
+		{"create invalid", "POST", "/auth/admin/users", admin, model.User{Email: "bad", Password: "short", Role: "root"}, http.StatusBadRequest},
+		{"disable", "POST", path + "/disable", admin, nil, http.StatusOK},
+		{"enable", "POST", path + "/enable", admin, nil, http.StatusOK},
+		{"password", "POST", path + "/password", admin, model.User{Password: "password2"}, http.StatusOK},
+		{"short password", "POST", path + "/password", admin, model.User{Password: "short"}, http.StatusBadRequest},
+		{"role", "POST", path + "/role", admin, model.User{Role: model.RoleAdmin}, http.StatusOK},
+		{"unknown role", "POST", path + "/role", admin, model.User{Role: "root"}, http.StatusBadRequest},
+		{"delete self", "DELETE", "/auth/admin/users/" + string(mustJSON(t, admin)), admin, nil, http.StatusBadRequest},
+		{"delete", "DELETE", path, admin, nil, http.StatusOK},
+		{"delete missing", "DELETE", path, admin, nil, http.StatusNotFound},
+		{"audit", "GET", "/auth/admin/audit?limit=10", admin, nil, http.StatusOK},
+		{"audit bad filter", "GET", "/auth/admin/audit?limit=0", admin, nil, http.StatusBadRequest},
+		{"lockouts", "GET", "/auth/admin/lockouts", admin, nil, http.StatusOK},
+		{"unlock", "POST", "/auth/admin/lockouts/unlock", admin, map[string]string{"email": "client@example.ru"}, http.StatusOK},
+		{"unlock nothing", "POST", "/auth/admin/lockouts/unlock", admin, map[string]string{}, http.StatusBadRequest},
+		{"ratelimits", "GET", "/auth/admin/ratelimits", admin, nil, http.StatusOK},
+	})
+
+	//password change takes effect on authentication
+	login := func(password string) int {
+		return ts.do(t, "POST", "/authentication", 0, map[string]string{"email": "client@example.ru", "password": password}).Code
+	}
+	ts.check(t, []routeCase{{"client password", "POST", "/auth/admin/users/" + string(mustJSON(t, client)) + "/password", admin, model.User{Password: "password3"}, http.StatusOK}})
+	if login("password") != http.StatusUnauthorized || login("password3") != http.StatusOK {
+		t.Error("changed password is not used by authentication")
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
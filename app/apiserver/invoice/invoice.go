@@ -0,0 +1,121 @@
+package invoice
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//default bill template
+//go:embed templates/bill.tmpl
+var defaultTemplate embed.FS
+
+//Generator of bill pdf
+type Generator struct {
+	font    *font
+	tpl     *template.Template
+	config  *model.Service
+	vatRate int
+}
+
+//New generator from config, font is required
+func New(config *model.Service) (*Generator, error) {
+
+	f, err := loadFont(config.Spec.Bill.Font)
+	if err != nil {
+		return nil, err
+	}
+
+	var tpl *template.Template
+	if config.Spec.Bill.Template != "" {
+		tpl, err = template.ParseFiles(config.Spec.Bill.Template)
+	} else {
+		tpl, err = template.ParseFS(defaultTemplate, "templates/bill.tmpl")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vatRate := config.Spec.Bill.Vat
+	if vatRate == 0 {
+		vatRate = 20
+	}
+
+	return &Generator{
+		font:    f,
+		tpl:     tpl,
+		config:  config,
+		vatRate: vatRate,
+	}, nil
+}
+
+//template data
+type billData struct {
+	Booking model.DataBooking
+	Seller  interface{}
+	Date    string
+	Price   string
+	Vat     string
+	VatRate int
+}
+
+//Bill pdf of booking, price includes vat
+func (g *Generator) Bill(data model.DataBooking, date time.Time) ([]byte, error) {
+
+	//kopecks
+	price := int64(data.PriceWithNds) * 100
+	vat := price * int64(g.vatRate) / int64(100+g.vatRate)
+
+	var text bytes.Buffer
+	if err := g.tpl.Execute(&text, billData{
+		Booking: data,
+		Seller:  g.config.Spec.Bill.Seller,
+		Date:    date.Format("02.01.2006"),
+		Price:   money(price),
+		Vat:     money(vat),
+		VatRate: g.vatRate,
+	}); err != nil {
+		return nil, err
+	}
+
+	d := newDocument(g.font)
+	for _, line := range strings.Split(text.String(), "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+			d.advance(8)
+		case line == "---":
+			d.rule()
+		case strings.HasPrefix(line, "# "):
+			d.advance(6)
+			d.paragraph(14, line[2:], "")
+			d.advance(4)
+		default:
+			parts := strings.SplitN(line, "\t", 2)
+			if len(parts) == 2 {
+				d.paragraph(10, parts[0], parts[1])
+			} else {
+				d.paragraph(10, line, "")
+			}
+		}
+	}
+
+	return d.bytes()
+}
+
+//kopecks to "1 234 567,89"
+func money(v int64) string {
+	rub := fmt.Sprintf("%d", v/100)
+	var b strings.Builder
+	for i, c := range rub {
+		if i > 0 && (len(rub)-i)%3 == 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(c)
+	}
+	return fmt.Sprintf("%s,%02d", b.String(), v%100)
+}
@@ -0,0 +1,220 @@
+package invoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//a4 page, points
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+)
+
+//pdf document with one embedded truetype font (Identity-H, cid = glyph id)
+type document struct {
+	font  *font
+	used  map[uint16]rune
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newDocument(f *font) *document {
+	d := &document{
+		font: f,
+		used: map[uint16]rune{},
+	}
+	d.newPage()
+	return d
+}
+
+func (d *document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+func (d *document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+//move cursor down, new page on overflow
+func (d *document) advance(h float64) {
+	if d.y-h < margin {
+		d.newPage()
+	}
+	d.y -= h
+}
+
+//text width in points
+func (d *document) width(s string, size float64) float64 {
+	w := 0
+	for _, r := range s {
+		w += d.font.width(d.font.glyph(r))
+	}
+	return float64(w) * size / 1000
+}
+
+//text at position
+func (d *document) text(x float64, y float64, size float64, s string) {
+	var hex strings.Builder
+	for _, r := range s {
+		g := d.font.glyph(r)
+		d.used[g] = r
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+	fmt.Fprintf(d.page(), "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, hex.String())
+}
+
+//horizontal rule at cursor
+func (d *document) rule() {
+	d.advance(6)
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, d.y, pageWidth-margin, d.y)
+	d.advance(6)
+}
+
+//paragraph wrapped to page width, right part aligned to right margin
+func (d *document) paragraph(size float64, left string, right string) {
+
+	maxWidth := pageWidth - 2*margin
+	if right != "" {
+		maxWidth -= d.width(right, size) + 10
+	}
+
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(left) {
+		next := strings.TrimSpace(line + " " + word)
+		if line != "" && d.width(next, size) > maxWidth {
+			lines = append(lines, line)
+			next = word
+		}
+		line = next
+	}
+	lines = append(lines, line)
+
+	for i, l := range lines {
+		d.advance(size * 1.4)
+		d.text(margin, d.y, size, l)
+		if i == 0 && right != "" {
+			d.text(pageWidth-margin-d.width(right, size), d.y, size, right)
+		}
+	}
+}
+
+//serialize pdf
+func (d *document) bytes() ([]byte, error) {
+
+	var objects [][]byte
+	add := func(body []byte) int {
+		objects = append(objects, body)
+		return len(objects)
+	}
+	stream := func(dict string, data []byte) ([]byte, error) {
+		var z bytes.Buffer
+		w := zlib.NewWriter(&z)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "<< %s /Filter /FlateDecode /Length %d >>\nstream\n", dict, z.Len())
+		b.Write(z.Bytes())
+		b.WriteString("\nendstream")
+		return b.Bytes(), nil
+	}
+
+	//fixed object numbers: 1 catalog, 2 pages, 3 font, 4 cid font, 5 descriptor, 6 font file, 7 to unicode
+	for i := 0; i < 7; i++ {
+		add(nil)
+	}
+
+	//outlines of used glyphs only
+	fontData := d.font.subset(d.used)
+	fontFile, err := stream(fmt.Sprintf("/Length1 %d", len(fontData)), fontData)
+	if err != nil {
+		return nil, err
+	}
+	objects[5] = fontFile
+
+	glyphs := make([]int, 0, len(d.used))
+	for g := range d.used {
+		glyphs = append(glyphs, int(g))
+	}
+	sort.Ints(glyphs)
+
+	var widths, cmap strings.Builder
+	fmt.Fprintf(&cmap, "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n")
+	fmt.Fprintf(&cmap, "/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	fmt.Fprintf(&cmap, "/CMapName /Adobe-Identity-UCS def /CMapType 2 def\n")
+	fmt.Fprintf(&cmap, "1 begincodespacerange <0000> <FFFF> endcodespacerange\n")
+	for i := 0; i < len(glyphs); i += 100 {
+		chunk := glyphs[i:]
+		if len(chunk) > 100 {
+			chunk = chunk[:100]
+		}
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(chunk))
+		for _, g := range chunk {
+			fmt.Fprintf(&cmap, "<%04X> <%04X>\n", g, d.used[uint16(g)])
+		}
+		fmt.Fprintf(&cmap, "endbfchar\n")
+	}
+	fmt.Fprintf(&cmap, "endcmap CMapName currentdict /CMap defineresource pop end end")
+	for _, g := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", g, d.font.width(uint16(g)))
+	}
+
+	toUnicode, err := stream("", []byte(cmap.String()))
+	if err != nil {
+		return nil, err
+	}
+	objects[6] = toUnicode
+
+	objects[2] = []byte("<< /Type /Font /Subtype /Type0 /BaseFont /InvoiceFont /Encoding /Identity-H " +
+		"/DescendantFonts [4 0 R] /ToUnicode 7 0 R >>")
+	objects[3] = []byte(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /InvoiceFont "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor 5 0 R /CIDToGIDMap /Identity /W [%s] >>", widths.String()))
+	objects[4] = []byte(fmt.Sprintf("<< /Type /FontDescriptor /FontName /InvoiceFont /Flags 32 "+
+		"/FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 6 0 R >>",
+		d.font.scale(d.font.bbox[0]), d.font.scale(d.font.bbox[1]), d.font.scale(d.font.bbox[2]), d.font.scale(d.font.bbox[3]),
+		d.font.scale(d.font.ascent), d.font.scale(d.font.descent), d.font.scale(d.font.ascent)))
+
+	kids := []string{}
+	for _, p := range d.pages {
+		content, err := stream("", p.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		c := add(content)
+		page := add([]byte(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, c)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+
+	objects[0] = []byte("<< /Type /Catalog /Pages 2 0 R >>")
+	objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(o)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,368 @@
+package invoice
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"sort"
+)
+
+//errors
+var (
+	errFont = errors.New("unsupported truetype font")
+)
+
+//tables copied to embedded subset, others are not used by pdf viewers
+var subsetTables = []string{"head", "hhea", "hmtx", "maxp", "cvt ", "fpgm", "prep"}
+
+//truetype font, tables needed for pdf embedding only
+type font struct {
+	tables     map[string][]byte
+	unitsPerEm int
+	bbox       [4]int
+	ascent     int
+	descent    int
+	advances   []int
+	cmap       []byte //format 4 subtable
+	glyf       []byte
+	loca       []int //glyph offsets in glyf, numGlyphs+1
+}
+
+//load truetype font file
+func loadFont(path string) (*font, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFont(data)
+}
+
+//parse and validate truetype font, all offsets used later are checked here
+func parseFont(data []byte) (*font, error) {
+
+	if len(data) < 12 {
+		return nil, errFont
+	}
+
+	tables := map[string][]byte{}
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		rec := 12 + i*16
+		if rec+16 > len(data) {
+			return nil, errFont
+		}
+		off := int64(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int64(binary.BigEndian.Uint32(data[rec+12:]))
+		if off+length > int64(len(data)) {
+			return nil, errFont
+		}
+		tables[string(data[rec:rec+4])] = data[off : off+length]
+	}
+
+	head, hhea, hmtx, maxp := tables["head"], tables["hhea"], tables["hmtx"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 || tables["glyf"] == nil {
+		return nil, errFont
+	}
+
+	f := &font{
+		tables:     tables,
+		unitsPerEm: int(binary.BigEndian.Uint16(head[18:])),
+		ascent:     int(int16(binary.BigEndian.Uint16(hhea[4:]))),
+		descent:    int(int16(binary.BigEndian.Uint16(hhea[6:]))),
+		glyf:       tables["glyf"],
+	}
+	if f.unitsPerEm < 16 || f.unitsPerEm > 16384 {
+		return nil, errFont
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+i*2:])))
+	}
+
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if metrics == 0 || len(hmtx) < metrics*4 {
+		return nil, errFont
+	}
+	f.advances = make([]int, metrics)
+	for i := range f.advances {
+		f.advances[i] = int(binary.BigEndian.Uint16(hmtx[i*4:]))
+	}
+
+	if err := f.parseLoca(tables["loca"], int(binary.BigEndian.Uint16(maxp[4:])), binary.BigEndian.Uint16(head[50:])); err != nil {
+		return nil, err
+	}
+	if err := f.parseCmap(tables["cmap"]); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+//glyph offsets, short or long loca format
+func (f *font) parseLoca(loca []byte, glyphs int, format uint16) error {
+
+	size := 2
+	if format == 1 {
+		size = 4
+	} else if format != 0 {
+		return errFont
+	}
+	if glyphs == 0 || len(loca) < (glyphs+1)*size {
+		return errFont
+	}
+
+	f.loca = make([]int, glyphs+1)
+	for i := range f.loca {
+		if size == 2 {
+			f.loca[i] = int(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		} else {
+			f.loca[i] = int(binary.BigEndian.Uint32(loca[i*4:]))
+		}
+		if f.loca[i] > len(f.glyf) || (i > 0 && f.loca[i] < f.loca[i-1]) {
+			return errFont
+		}
+	}
+
+	return nil
+}
+
+//unicode bmp subtable: windows (3,1) or unicode (0,3), format 4
+func (f *font) parseCmap(cmap []byte) error {
+
+	if len(cmap) < 4 {
+		return errFont
+	}
+
+	records := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < records; i++ {
+		rec := 4 + i*8
+		if rec+8 > len(cmap) {
+			return errFont
+		}
+		platform, encoding := binary.BigEndian.Uint16(cmap[rec:]), binary.BigEndian.Uint16(cmap[rec+2:])
+		off := int64(binary.BigEndian.Uint32(cmap[rec+4:]))
+		if !(platform == 3 && encoding == 1) && !(platform == 0 && encoding == 3) {
+			continue
+		}
+		if off+14 > int64(len(cmap)) || binary.BigEndian.Uint16(cmap[off:]) != 4 {
+			continue
+		}
+		//ends, reserved pad, starts, deltas, range offsets
+		sub := cmap[off:]
+		segX2 := int(binary.BigEndian.Uint16(sub[6:]))
+		if segX2%2 != 0 || len(sub) < 16+segX2*4 {
+			return errFont
+		}
+		f.cmap = sub
+		return nil
+	}
+
+	return errFont
+}
+
+//glyph id of rune, 0 - missing glyph
+func (f *font) glyph(r rune) uint16 {
+
+	if r > 0xFFFF {
+		return 0
+	}
+	c := uint16(r)
+
+	segX2 := int(binary.BigEndian.Uint16(f.cmap[6:]))
+	ends := 14
+	starts := ends + segX2 + 2
+	deltas := starts + segX2
+	ranges := deltas + segX2
+
+	for i := 0; i < segX2; i += 2 {
+		end := binary.BigEndian.Uint16(f.cmap[ends+i:])
+		if end < c {
+			continue
+		}
+		start := binary.BigEndian.Uint16(f.cmap[starts+i:])
+		if start > c {
+			return 0
+		}
+		delta := binary.BigEndian.Uint16(f.cmap[deltas+i:])
+		rangeOff := int(binary.BigEndian.Uint16(f.cmap[ranges+i:]))
+		if rangeOff == 0 {
+			return f.valid(c + delta)
+		}
+		addr := ranges + i + rangeOff + 2*int(c-start)
+		if addr+2 > len(f.cmap) {
+			return 0
+		}
+		g := binary.BigEndian.Uint16(f.cmap[addr:])
+		if g == 0 {
+			return 0
+		}
+		return f.valid(g + delta)
+	}
+
+	return 0
+}
+
+//glyph id present in font or missing glyph
+func (f *font) valid(g uint16) uint16 {
+	if int(g) >= len(f.loca)-1 {
+		return 0
+	}
+	return g
+}
+
+//advance width of glyph in 1/1000 em, last advance repeats
+func (f *font) width(g uint16) int {
+	i := int(g)
+	if i >= len(f.advances) {
+		i = len(f.advances) - 1
+	}
+	return f.advances[i] * 1000 / f.unitsPerEm
+}
+
+//scale font units to 1/1000 em
+func (f *font) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+//glyph ids referenced by composite glyph
+func (f *font) components(g int) []int {
+
+	data := f.glyf[f.loca[g]:f.loca[g+1]]
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+
+	var ids []int
+	for p := 10; p+4 <= len(data); {
+		flags := binary.BigEndian.Uint16(data[p:])
+		ids = append(ids, int(binary.BigEndian.Uint16(data[p+2:])))
+		p += 4
+		//arguments: words or bytes
+		if flags&0x0001 != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		//transform: scale, x and y scale, 2x2
+		switch {
+		case flags&0x0008 != 0:
+			p += 2
+		case flags&0x0040 != 0:
+			p += 4
+		case flags&0x0080 != 0:
+			p += 8
+		}
+		if flags&0x0020 == 0 {
+			break
+		}
+	}
+
+	return ids
+}
+
+//font file with outlines of used glyphs only, glyph ids are kept,
+//so cid to gid mapping stays identity
+func (f *font) subset(used map[uint16]rune) []byte {
+
+	glyphs := len(f.loca) - 1
+	keep := map[int]bool{}
+	var visit func(g int)
+	visit = func(g int) {
+		if g >= glyphs || keep[g] {
+			return
+		}
+		keep[g] = true
+		for _, c := range f.components(g) {
+			visit(c)
+		}
+	}
+	visit(0)
+	for g := range used {
+		visit(int(g))
+	}
+
+	//long loca format
+	glyf := []byte{}
+	loca := make([]byte, (glyphs+1)*4)
+	for g := 0; g < glyphs; g++ {
+		binary.BigEndian.PutUint32(loca[g*4:], uint32(len(glyf)))
+		if keep[g] {
+			glyf = append(glyf, f.glyf[f.loca[g]:f.loca[g+1]]...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[glyphs*4:], uint32(len(glyf)))
+
+	tables := map[string][]byte{"glyf": glyf, "loca": loca}
+	for _, tag := range subsetTables {
+		if t, ok := f.tables[tag]; ok {
+			tables[tag] = t
+		}
+	}
+	head := append([]byte{}, tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	tables["head"] = head
+
+	return sfnt(tables)
+}
+
+//truetype file of tables
+func sfnt(tables map[string][]byte) []byte {
+
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	entry, selector := 1, 0
+	for entry*2 <= n {
+		entry *= 2
+		selector++
+	}
+
+	out := make([]byte, 12+n*16)
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(n))
+	binary.BigEndian.PutUint16(out[6:], uint16(entry*16))
+	binary.BigEndian.PutUint16(out[8:], uint16(selector))
+	binary.BigEndian.PutUint16(out[10:], uint16((n-entry)*16))
+
+	headOff := 0
+	for i, tag := range tags {
+		t := tables[tag]
+		rec := out[12+i*16:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], checksum(t))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(t)))
+		if tag == "head" {
+			headOff = len(out)
+		}
+		out = append(out, t...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+
+	//whole font checksum adjustment
+	binary.BigEndian.PutUint32(out[headOff+8:], 0xB1B0AFBA-checksum(out))
+
+	return out
+}
+
+//table checksum, sum of big endian words padded with zeros
+func checksum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(b); i += 4 {
+		var word [4]byte
+		copy(word[:], b[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
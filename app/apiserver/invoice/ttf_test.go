@@ -0,0 +1,206 @@
+package invoice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+func be16(b []byte, v int) {
+	binary.BigEndian.PutUint16(b, uint16(v))
+}
+
+//tables of minimal font: 'A' - glyph 1, 'B' - composite glyph 2 of glyph 1,
+//glyph 3 is not mapped, 3 advances for 4 glyphs
+func testTables() map[string][]byte {
+
+	head := make([]byte, 54)
+	be16(head[18:], 1000)
+	be16(head[36:], -50)
+	be16(head[38:], -200)
+	be16(head[40:], 900)
+	be16(head[42:], 800)
+
+	hhea := make([]byte, 36)
+	be16(hhea[4:], 800)
+	be16(hhea[6:], -200)
+	be16(hhea[34:], 3)
+
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	be16(maxp[4:], 4)
+
+	hmtx := make([]byte, 12)
+	be16(hmtx[0:], 500)
+	be16(hmtx[4:], 600)
+	be16(hmtx[8:], 700)
+
+	simple := bytes.Repeat([]byte{0x11}, 12)
+	composite := make([]byte, 16)
+	be16(composite, -1)
+	be16(composite[12:], 1) //flags 0: byte arguments, last component
+	glyf := append(append(append(append([]byte{}, simple...), simple...), composite...), bytes.Repeat([]byte{0x33}, 40)...)
+
+	//short format, offsets / 2
+	loca := make([]byte, 10)
+	for i, off := range []int{0, 12, 24, 40, 80} {
+		be16(loca[i*2:], off/2)
+	}
+
+	//format 4: segment 'A'-'B' by delta, terminal segment
+	sub := make([]byte, 16+4*4)
+	be16(sub, 4)
+	be16(sub[2:], len(sub))
+	be16(sub[6:], 4)
+	be16(sub[14:], 'B')
+	be16(sub[16:], 0xFFFF)
+	be16(sub[20:], 'A')
+	be16(sub[22:], 0xFFFF)
+	be16(sub[24:], 1-'A')
+	be16(sub[26:], 1)
+	cmap := make([]byte, 12)
+	be16(cmap[2:], 1)
+	be16(cmap[4:], 3)
+	be16(cmap[6:], 1)
+	binary.BigEndian.PutUint32(cmap[8:], 12)
+	cmap = append(cmap, sub...)
+
+	return map[string][]byte{"head": head, "hhea": hhea, "maxp": maxp, "hmtx": hmtx, "glyf": glyf, "loca": loca, "cmap": cmap}
+}
+
+func testFont(t *testing.T) *font {
+	f, err := parseFont(sfnt(testTables()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+//tables of font file
+func fontTables(data []byte) map[string][]byte {
+	tables := map[string][]byte{}
+	for i := 0; i < int(binary.BigEndian.Uint16(data[4:])); i++ {
+		rec := data[12+i*16:]
+		off, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		tables[string(rec[:4])] = data[off : off+length]
+	}
+	return tables
+}
+
+func TestParseFont(t *testing.T) {
+
+	f := testFont(t)
+
+	for r, g := range map[rune]uint16{'A': 1, 'B': 2, 'C': 0, 'Я': 0, 0x1F600: 0} {
+		if got := f.glyph(r); got != g {
+			t.Errorf("glyph %q: %d, want %d", r, got, g)
+		}
+	}
+	if f.width(1) != 600 || f.width(3) != 700 || f.width(100) != 700 {
+		t.Errorf("widths %d %d %d", f.width(1), f.width(3), f.width(100))
+	}
+	if f.bbox != [4]int{-50, -200, 900, 800} || f.ascent != 800 || f.descent != -200 {
+		t.Errorf("metrics %v %d %d", f.bbox, f.ascent, f.descent)
+	}
+}
+
+func TestParseFontMalformed(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		change func(tables map[string][]byte)
+	}{
+		{"units per em zero", func(tb map[string][]byte) { be16(tb["head"][18:], 0) }},
+		{"no metrics", func(tb map[string][]byte) { be16(tb["hhea"][34:], 0) }},
+		{"short hmtx", func(tb map[string][]byte) { tb["hmtx"] = tb["hmtx"][:8] }},
+		{"no glyf", func(tb map[string][]byte) { delete(tb, "glyf") }},
+		{"loca past glyf", func(tb map[string][]byte) { be16(tb["loca"][8:], 100) }},
+		{"loca format", func(tb map[string][]byte) { be16(tb["head"][50:], 2) }},
+		{"short cmap", func(tb map[string][]byte) { tb["cmap"] = tb["cmap"][:2] }},
+		{"cmap records past end", func(tb map[string][]byte) { be16(tb["cmap"][2:], 100); be16(tb["cmap"][4:], 1) }},
+		{"cmap subtable past end", func(tb map[string][]byte) { binary.BigEndian.PutUint32(tb["cmap"][8:], 1000) }},
+		{"cmap segments past end", func(tb map[string][]byte) { be16(tb["cmap"][12+6:], 40) }},
+		{"cmap no unicode", func(tb map[string][]byte) { be16(tb["cmap"][4:], 1) }},
+	}
+
+	for _, tt := range tests {
+		tables := testTables()
+		tt.change(tables)
+		if _, err := parseFont(sfnt(tables)); err != errFont {
+			t.Errorf("%s: error %v, want errFont", tt.name, err)
+		}
+	}
+
+	//truncated file fails or parses, never panics
+	data := sfnt(testTables())
+	for n := 0; n < len(data); n++ {
+		if f, err := parseFont(data[:n]); err == nil {
+			f.glyph('A')
+			f.subset(map[uint16]rune{1: 'A'})
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+
+	f := testFont(t)
+	data := f.subset(map[uint16]rune{2: 'B'})
+
+	if checksum(data) != 0xB1B0AFBA {
+		t.Errorf("font checksum %08X", checksum(data))
+	}
+
+	tables := fontTables(data)
+	if _, ok := tables["cmap"]; ok {
+		t.Error("cmap must not be embedded")
+	}
+	sub := &font{glyf: tables["glyf"]}
+	if err := sub.parseLoca(tables["loca"], 4, binary.BigEndian.Uint16(tables["head"][50:])); err != nil {
+		t.Fatal(err)
+	}
+	//glyph 0 and component glyph 1 are kept, unused glyph 3 is dropped
+	for g, size := range []int{12, 12, 16, 0} {
+		if got := sub.loca[g+1] - sub.loca[g]; got != size {
+			t.Errorf("glyph %d: %d bytes, want %d", g, got, size)
+		}
+	}
+}
+
+func TestMoney(t *testing.T) {
+
+	for v, want := range map[int64]string{0: "0,00", 5: "0,05", 123456: "1 234,56", 250000000: "2 500 000,00"} {
+		if got := money(v); got != want {
+			t.Errorf("money(%d) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestBill(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "font.ttf")
+	if err := ioutil.WriteFile(path, sfnt(testTables()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := &model.Service{}
+	config.Spec.Bill.Font = path
+	g, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := g.Bill(model.DataBooking{RequestId: "r1", BillNumber: "B-1", PriceWithNds: 2500000}, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Error("not a pdf document")
+	}
+	if !bytes.Contains(pdf, []byte("/FontFile2 6 0 R")) {
+		t.Error("font is not embedded")
+	}
+}
@@ -0,0 +1,230 @@
+package mailing
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//defaults for zero config values
+const (
+	defaultWorkers   = 2
+	defaultQueueSize = 100
+	defaultRetries   = 5
+	defaultBackoff   = 2 * time.Second
+)
+
+//delivery statuses
+const (
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" //no mailing consent
+)
+
+//Recorder delivery status storage
+type Recorder interface {
+	QueryInsertMailingStatusPostgres(model.MailingStatus) error
+}
+
+//Queue background mailing of booking confirmations
+type Queue struct {
+	mu        sync.Mutex
+	closed    bool
+	workers   sync.WaitGroup
+	records   sync.WaitGroup //statuses recorded off the request path
+	jobs      chan model.DataBooking
+	sender    Sender
+	recorder  Recorder
+	templates *templates
+	from      string
+	retries   int
+	backoff   time.Duration
+}
+
+//New queue from config, workers are started
+func New(config *model.Service, recorder Recorder) *Queue {
+
+	var sender Sender
+	switch config.Spec.Mailing.Transport {
+	case "smtp":
+		sender = &SMTPSender{
+			Addr:     config.Spec.Mailing.SmtpAddr,
+			User:     config.Spec.Mailing.SmtpUser,
+			Password: config.Spec.Mailing.SmtpPassword,
+		}
+	case "memory":
+		sender = &MemorySender{}
+	default:
+		sender = &HTTPSender{
+			Url:    config.Spec.Client.UrlMailingService,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	return NewWithSender(config, recorder, sender)
+}
+
+//NewWithSender queue with given transport, workers are started
+func NewWithSender(config *model.Service, recorder Recorder, sender Sender) *Queue {
+
+	workers := config.Spec.Mailing.Workers
+	if workers == 0 {
+		workers = defaultWorkers
+	}
+	size := config.Spec.Mailing.QueueSize
+	if size == 0 {
+		size = defaultQueueSize
+	}
+	retries := config.Spec.Mailing.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+
+	q := &Queue{
+		jobs:      make(chan model.DataBooking, size),
+		sender:    sender,
+		recorder:  recorder,
+		templates: &templates{dir: config.Spec.Mailing.Templates},
+		from:      config.Spec.Mailing.From,
+		retries:   retries,
+		backoff:   defaultBackoff,
+	}
+
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+
+	return q
+}
+
+//Enqueue booking confirmation, never blocks, statuses of not queued bookings
+//are stored in background, so postgres doesn't delay the request
+func (q *Queue) Enqueue(data model.DataBooking) {
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.record(data, StatusFailed, 0, "mailing queue closed")
+		return
+	}
+
+	if data.Consentmailing != "yes" {
+		q.recordAsync(data, StatusSkipped, "")
+		return
+	}
+
+	select {
+	case q.jobs <- data:
+	default:
+		q.recordAsync(data, StatusFailed, "mailing queue full")
+	}
+}
+
+//record status in background, locked, queue is open
+func (q *Queue) recordAsync(data model.DataBooking, status string, errText string) {
+	q.records.Add(1)
+	go func() {
+		defer q.records.Done()
+		q.record(data, status, 0, errText)
+	}()
+}
+
+//Close stops accepting bookings and waits until queued ones are delivered
+//and statuses are recorded or ctx is done
+func (q *Queue) Close(ctx context.Context) error {
+
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		q.records.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		logger.ErrorLogger.Printf("mailing queue closed with %d undelivered", len(q.jobs))
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+
+	for data := range q.jobs {
+		q.deliver(data)
+	}
+}
+
+//render and send with retries
+func (q *Queue) deliver(data model.DataBooking) {
+
+	subject, body, err := q.templates.render(data)
+	if err != nil {
+		q.record(data, StatusFailed, 0, err.Error())
+		return
+	}
+
+	m := Message{
+		RequestId: data.RequestId,
+		From:      q.from,
+		To:        data.Email,
+		Subject:   subject,
+		Body:      body,
+		Booking:   data,
+	}
+
+	attempt := 0
+	for {
+		attempt++
+		err = q.sender.Send(m)
+		if err == nil || attempt > q.retries {
+			break
+		}
+		logger.WarningLogger.Printf("mailing %s attempt %d: %v", data.RequestId, attempt, err)
+		time.Sleep(q.backoff << uint(attempt-1))
+	}
+
+	if err != nil {
+		q.record(data, StatusFailed, attempt, err.Error())
+		return
+	}
+	q.record(data, StatusSent, attempt, "")
+}
+
+func (q *Queue) record(data model.DataBooking, status string, attempts int, errText string) {
+
+	s := model.MailingStatus{
+		RequestId:  data.RequestId,
+		Email:      data.Email,
+		ActionType: data.ActionType,
+		BrandName:  data.BrandName,
+		Status:     status,
+		Attempts:   attempts,
+		Error:      errText,
+	}
+
+	if status == StatusFailed {
+		logger.ErrorLogger.Printf("mailing %s: %s", data.RequestId, errText)
+	} else {
+		logger.InfoLogger.Printf("mailing %s: %s", data.RequestId, status)
+	}
+
+	if err := q.recorder.QueryInsertMailingStatusPostgres(s); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+}
@@ -0,0 +1,171 @@
+package mailing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//recorder of delivery statuses
+type statuses struct {
+	mu   sync.Mutex
+	list []model.MailingStatus
+}
+
+func (s *statuses) QueryInsertMailingStatusPostgres(m model.MailingStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.list = append(s.list, m)
+	return nil
+}
+
+func (s *statuses) count(status string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, m := range s.list {
+		if m.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+func booking(id string) model.DataBooking {
+	return model.DataBooking{RequestId: id, ActionType: "form", Email: "client@example.ru", Consentmailing: "yes"}
+}
+
+func TestHTTPSenderPostsMessage(t *testing.T) {
+
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	//brand template overrides default of action type
+	dir := t.TempDir()
+	tpl := `{{define "subject"}}Заявка {{.RequestId}}{{end}}{{define "body"}}<p>{{.Name}}</p>{{end}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "form_gaz.html"), []byte(tpl), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &model.Service{}
+	config.Spec.Client.UrlMailingService = srv.URL
+	config.Spec.Mailing.Workers = 1
+	config.Spec.Mailing.Templates = dir
+	config.Spec.Mailing.From = "noreply@example.ru"
+	q := New(config, &statuses{})
+
+	data := booking("r1")
+	data.BrandName = "GAZ"
+	data.Name = "Иван"
+	q.Enqueue(data)
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//booking fields keep the service contract
+	if got["request_id"] != "r1" || got["client_email"] != "client@example.ru" || got["action_type"] != "form" {
+		t.Errorf("mailing service got %v, want booking json", got)
+	}
+	want := map[string]string{
+		"mail_from":    "noreply@example.ru",
+		"mail_to":      "client@example.ru",
+		"mail_subject": "Заявка r1",
+		"mail_body":    "<p>Иван</p>",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestNotQueuedStatuses(t *testing.T) {
+
+	config := &model.Service{}
+	config.Spec.Mailing.Workers = 1
+	config.Spec.Mailing.QueueSize = 1
+	release := make(chan struct{})
+	rec := &statuses{}
+	q := NewWithSender(config, rec, &MemorySender{Fail: func(Message) error {
+		<-release
+		return nil
+	}})
+
+	//r1 is taken by worker, r2 fills queue, r3 doesn't fit
+	q.Enqueue(booking("r1"))
+	for len(q.jobs) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	q.Enqueue(booking("r2"))
+	q.Enqueue(booking("r3"))
+	noConsent := booking("r4")
+	noConsent.Consentmailing = "no"
+	q.Enqueue(noConsent)
+
+	close(release)
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rec.count(StatusSent) != 2 || rec.count(StatusFailed) != 1 || rec.count(StatusSkipped) != 1 {
+		t.Errorf("statuses %+v", rec.list)
+	}
+}
+
+func TestCloseDrainsQueue(t *testing.T) {
+
+	config := &model.Service{}
+	config.Spec.Mailing.Workers = 1
+	sender := &MemorySender{Fail: func(Message) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+	rec := &statuses{}
+	q := NewWithSender(config, rec, sender)
+
+	for _, id := range []string{"r1", "r2", "r3"} {
+		q.Enqueue(booking(id))
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(sender.Messages()); n != 3 {
+		t.Errorf("%d messages sent, want 3", n)
+	}
+
+	q.Enqueue(booking("r4"))
+	if n := rec.count(StatusFailed); n != 1 {
+		t.Errorf("%d failed statuses, enqueue after close must fail", n)
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+
+	config := &model.Service{}
+	config.Spec.Mailing.Workers = 1
+	release := make(chan struct{})
+	defer close(release)
+	q := NewWithSender(config, &statuses{}, &MemorySender{Fail: func(Message) error {
+		<-release
+		return nil
+	}})
+
+	q.Enqueue(booking("r1"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("error %v, want deadline exceeded", err)
+	}
+}
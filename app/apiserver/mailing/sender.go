@@ -0,0 +1,132 @@
+package mailing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//Message email
+type Message struct {
+	RequestId string
+	From      string
+	To        string
+	Subject   string
+	Body      string            //html
+	Booking   model.DataBooking //source booking, sent to mailing microservice with message
+}
+
+//Sender email transport
+type Sender interface {
+	Send(Message) error
+}
+
+//HTTPSender mailing microservice
+type HTTPSender struct {
+	Url    string
+	Client *http.Client
+}
+
+//mailing microservice payload: booking json with rendered message fields
+type httpMessage struct {
+	model.DataBooking
+	MailFrom    string `json:"mail_from"`
+	MailTo      string `json:"mail_to"`
+	MailSubject string `json:"mail_subject"`
+	MailBody    string `json:"mail_body"` //html of template by action_type and brand
+}
+
+//Send post booking with rendered message to mailing microservice,
+//booking fields keep the service's contract, mail fields are added
+func (s *HTTPSender) Send(m Message) error {
+
+	body, err := json.Marshal(httpMessage{
+		DataBooking: m.Booking,
+		MailFrom:    m.From,
+		MailTo:      m.To,
+		MailSubject: m.Subject,
+		MailBody:    m.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.Client.Post(s.Url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("mailing service status %d: %s", resp.StatusCode, respBody)
+	}
+
+	return nil
+}
+
+//SMTPSender smtp server
+type SMTPSender struct {
+	Addr     string //host:port
+	User     string
+	Password string
+}
+
+//Send message via smtp
+func (s *SMTPSender) Send(m Message) error {
+
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Password, strings.Split(s.Addr, ":")[0])
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + m.From + "\r\n")
+	b.WriteString("To: " + m.To + "\r\n")
+	b.WriteString("Subject: " + mimeHeader(m.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+
+	return smtp.SendMail(s.Addr, auth, m.From, []string{m.To}, []byte(b.String()))
+}
+
+//MemorySender keeps messages in memory, stand-in for local runs and tests
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+	Fail     func(Message) error //optional failure injection
+}
+
+//Send store message
+func (s *MemorySender) Send(m Message) error {
+	if s.Fail != nil {
+		if err := s.Fail(m); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, m)
+
+	return nil
+}
+
+//Messages sent
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
@@ -0,0 +1,63 @@
+package mailing
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//default templates by action_type
+//
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+//templates with "subject" and "body" definitions
+//lookup: {dir}/{action_type}_{brand}.html, {dir}/{action_type}.html, default {action_type}.html
+type templates struct {
+	dir string
+}
+
+func (t *templates) lookup(actionType string, brand string) (*template.Template, error) {
+
+	if t.dir != "" {
+		brand = strings.ToLower(strings.ReplaceAll(brand, " ", "_"))
+		for _, name := range []string{actionType + "_" + brand + ".html", actionType + ".html"} {
+			f := filepath.Join(t.dir, filepath.Base(name))
+			if _, err := os.Stat(f); err == nil {
+				return template.ParseFiles(f)
+			}
+		}
+	}
+
+	return template.ParseFS(defaultTemplates, "templates/"+actionType+".html")
+}
+
+//render subject and body of booking
+func (t *templates) render(data model.DataBooking) (string, string, error) {
+
+	tpl, err := t.lookup(data.ActionType, data.BrandName)
+	if err != nil {
+		return "", "", err
+	}
+
+	var subject, body bytes.Buffer
+	if err := tpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
+
+//encode non-ascii header
+func mimeHeader(s string) string {
+	return mime.BEncoding.Encode("UTF-8", s)
+}
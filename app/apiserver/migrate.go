@@ -0,0 +1,68 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/migrate"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//Migrate postgres schema, command up, down or status
+func Migrate(config *model.Service, command string, out io.Writer) error {
+
+	dbPostgres, err := newDbPostgres(config)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	defer dbPostgres.Close()
+
+	m, err := migrate.New(dbPostgres)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mg := range done {
+			fmt.Fprintf(out, "applied %04d_%s\n", mg.Version, mg.Name)
+			logger.InfoLogger.Printf("migration applied %04d_%s", mg.Version, mg.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		mg, err := m.Down(ctx)
+		if mg != nil {
+			fmt.Fprintf(out, "rolled back %04d_%s\n", mg.Version, mg.Name)
+			logger.InfoLogger.Printf("migration rolled back %04d_%s", mg.Version, mg.Name)
+		}
+		if err == nil && mg == nil {
+			fmt.Fprintln(out, "no applied migrations")
+		}
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			if st.Applied {
+				fmt.Fprintf(out, "%04d_%s\tapplied %s\n", st.Version, st.Name, st.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Fprintf(out, "%04d_%s\tpending\n", st.Version, st.Name)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown migrate command %q, up, down or status", command)
+}
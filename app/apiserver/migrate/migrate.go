@@ -0,0 +1,311 @@
+package migrate
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//versioned migrations, {version}_{name}.up.sql and {version}_{name}.down.sql
+//baseline has no down migration, its rollback is refused
+//
+//go:embed migrations/*.sql
+var files embed.FS
+
+//advisory lock key, one migrator at a time
+const lockKey = 4807316
+
+//baseline version creates tables existing before migrations
+const baselineVersion = 1
+
+//errors
+var (
+	ErrBaseline = errors.New("baseline migration holds production tables and can not be rolled back")
+)
+
+const createTable = `
+create table if not exists schema_migrations (
+	version integer primary key,
+	name text not null,
+	applied_at timestamptz not null default now()
+)`
+
+//Migration sql of version
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+//Status of migration
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+//Migrator applies embedded migrations
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+//New migrator with embedded migrations
+func New(db *pgxpool.Pool) (*Migrator, error) {
+
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+//load migrations of dir migrations ordered by version
+func load(fsys fs.FS) ([]Migration, error) {
+
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: unknown direction", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		i := strings.Index(base, "_")
+		if i < 0 {
+			return nil, fmt.Errorf("migration %s: name must be version_name", name)
+		}
+		version, err := strconv.Atoi(base[:i])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %v", name, err)
+		}
+
+		sql, err := fs.ReadFile(fsys, path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: base[i+1:]}
+			byVersion[version] = m
+		}
+		if m.Name != base[i+1:] {
+			return nil, fmt.Errorf("migration %d: names differ", version)
+		}
+		if direction == "up" {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d: up required", m.Version)
+		}
+		if m.Version == baselineVersion && m.Down != "" {
+			return nil, fmt.Errorf("migration %d: baseline can not be rolled back, down not allowed", m.Version)
+		}
+		if m.Version != baselineVersion && m.Down == "" {
+			return nil, fmt.Errorf("migration %d: down required", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+//run f on locked connection with schema_migrations created
+func (m *Migrator) locked(ctx context.Context, f func(conn *pgxpool.Conn) error) error {
+
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "select pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+
+	defer conn.Exec(context.Background(), "select pg_advisory_unlock($1)", lockKey)
+
+	if _, err := conn.Exec(ctx, createTable); err != nil {
+		return err
+	}
+
+	return f(conn)
+}
+
+//applied versions
+func applied(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	versions := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		versions[version] = at
+	}
+
+	return versions, rows.Err()
+}
+
+//run migration sql and record version in one transaction
+func exec(ctx context.Context, conn *pgxpool.Conn, sql string, record func(tx pgx.Tx) error) error {
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+//Up applies pending migrations, returns applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+
+	var done []Migration
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if _, ok := versions[mg.Version]; ok {
+				continue
+			}
+			mg := mg
+			if err := exec(ctx, conn, mg.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, "insert into schema_migrations (version, name) values($1, $2)", mg.Version, mg.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+//Down rolls back last applied migration, nil if nothing applied, ErrBaseline if only baseline is applied
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+
+	var done *Migration
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := versions[mg.Version]; !ok {
+				continue
+			}
+			if mg.Version == baselineVersion {
+				return ErrBaseline
+			}
+			if err := exec(ctx, conn, mg.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, "delete from schema_migrations where version = $1", mg.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %v", mg.Version, mg.Name, err)
+			}
+			done = &mg
+			return nil
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+//Status of all migrations
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+
+	var statuses []Status
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			at, ok := versions[mg.Version]
+			statuses = append(statuses, Status{
+				Version:   mg.Version,
+				Name:      mg.Name,
+				Applied:   ok,
+				AppliedAt: at,
+			})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
@@ -0,0 +1,155 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//column definitions of table in up migrations: create table and add column
+func columns(t *testing.T, table string) map[string]string {
+
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := regexp.MustCompile(`(?s)create table (?:if not exists )?` + table + ` \((.*?)\n\);`)
+	add := regexp.MustCompile(`alter table ` + table + ` add column (?:if not exists )?(\w+) (\w+)`)
+
+	cols := map[string]string{}
+	for _, mg := range migrations {
+		if m := create.FindStringSubmatch(mg.Up); m != nil {
+			for _, line := range strings.Split(m[1], "\n") {
+				if f := strings.Fields(line); len(f) >= 2 {
+					cols[f[0]] = strings.TrimSuffix(f[1], ",")
+				}
+			}
+		}
+		for _, m := range add.FindAllStringSubmatch(mg.Up, -1) {
+			cols[m[1]] = m[2]
+		}
+	}
+	return cols
+}
+
+//every column is stored from model field of matching type
+func checkColumns(t *testing.T, table string, v interface{}, alias map[string]string) {
+
+	types := map[reflect.Kind]string{reflect.String: "text", reflect.Int: "integer", reflect.Bool: "boolean"}
+
+	fields := map[string]reflect.Kind{}
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.ToLower(typ.Field(i).Tag.Get("json"))
+		if a, ok := alias[name]; ok {
+			name = a
+		}
+		fields[name] = typ.Field(i).Type.Kind()
+	}
+
+	cols := columns(t, table)
+	if len(cols) == 0 {
+		t.Fatalf("%s: table not found", table)
+	}
+	for col, sqlType := range cols {
+		kind, ok := fields[col]
+		if !ok {
+			t.Errorf("%s.%s: no model field", table, col)
+			continue
+		}
+		if types[kind] != sqlType {
+			t.Errorf("%s.%s: %s column of %s field", table, col, sqlType, kind)
+		}
+	}
+}
+
+func TestBaselineMatchesModel(t *testing.T) {
+
+	checkColumns(t, "booking", model.DataBooking{}, map[string]string{"bill_namber": "bill_number", "clientid": "ymuid"})
+	checkColumns(t, "forms", model.DataForms{}, nil)
+}
+
+func TestLoad(t *testing.T) {
+
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, mg := range migrations {
+		if mg.Version != i+1 {
+			t.Errorf("migration %d_%s: versions must be contiguous", mg.Version, mg.Name)
+		}
+	}
+	//baseline holds production tables, it has no down
+	if migrations[0].Version != baselineVersion || migrations[0].Down != "" {
+		t.Errorf("baseline down: %q", migrations[0].Down)
+	}
+}
+
+func TestLoadRequiresDown(t *testing.T) {
+
+	sql := &fstest.MapFile{Data: []byte("select 1;")}
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		ok    bool
+	}{
+		{"baseline up only", fstest.MapFS{"migrations/0001_base.up.sql": sql}, true},
+		{"baseline down", fstest.MapFS{"migrations/0001_base.up.sql": sql, "migrations/0001_base.down.sql": sql}, false},
+		{"no down", fstest.MapFS{"migrations/0001_base.up.sql": sql, "migrations/0002_next.up.sql": sql}, false},
+		{"no up", fstest.MapFS{"migrations/0001_base.up.sql": sql, "migrations/0002_next.down.sql": sql}, false},
+		{"names differ", fstest.MapFS{"migrations/0001_base.up.sql": sql, "migrations/0002_next.up.sql": sql, "migrations/0002_other.down.sql": sql}, false},
+	}
+
+	for _, tt := range tests {
+		if _, err := load(tt.files); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v", tt.name, err)
+		}
+	}
+}
+
+//migrations against real postgres, TEST_POSTGRES_DSN of a disposable database
+func TestMigrateDown(t *testing.T) {
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+	db, err := pgxpool.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		mg, err := m.Down(ctx)
+		if err == ErrBaseline {
+			break
+		}
+		if err != nil || mg == nil {
+			t.Fatalf("down: %v, %v", mg, err)
+		}
+	}
+	if _, err := db.Exec(ctx, "select count(*) from booking"); err != nil {
+		t.Errorf("baseline table dropped: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
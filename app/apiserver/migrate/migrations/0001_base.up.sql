@@ -0,0 +1,127 @@
+-- tables existing before migrations, created if missing, existing tables are not changed
+-- columns follow positional inserts of the service before migrations, types follow the model
+-- baseline has no down migration, migrator refuses to roll it back
+create table if not exists users (
+	id serial primary key,
+	email text not null unique,
+	password text not null
+);
+
+create table if not exists booking (
+	request_id text not null,
+	action_type text not null default '',
+	uniq_mod_code integer not null default 0,
+	modification text not null default '',
+	mod_family text not null default '',
+	mod_body_type text not null default '',
+	mod_engine text not null default '',
+	mod_base text not null default '',
+	mod_tuning text not null default '',
+	vin text not null default '',
+	price integer not null default 0,
+	client_type text not null default '',
+	inn text not null default '',
+	kpp text not null default '',
+	ogrn text not null default '',
+	reg_address_code text not null default '',
+	delivery_address_code text not null default '',
+	delivery_address text not null default '',
+	hid text not null default '',
+	client_company_name text not null default '',
+	representative_name text not null default '',
+	representative_surname text not null default '',
+	surname text not null default '',
+	client_name text not null default '',
+	patronymic text not null default '',
+	passport_ser text not null default '',
+	passport_number text not null default '',
+	snils text not null default '',
+	date_of_birth text not null default '',
+	client_email text not null default '',
+	client_phone_number text not null default '',
+	commentary text not null default '',
+	agreement_mailing text not null default '',
+	event_datetime text not null default '',
+	file text not null default '',
+	bill_number text not null default '',
+	url_mod text not null default '',
+	clientid_google text not null default '',
+	ymuid text not null default ''
+);
+
+create index if not exists booking_request_id_idx on booking (request_id);
+
+create table if not exists forms (
+	event_datetime text not null default '',
+	request_id text not null,
+	subdivisions_id text not null default '',
+	subdivisions_name text not null default '',
+	form_name text not null default '',
+	id_form text not null default '',
+	host_name text not null default '',
+	division text not null default '',
+	area text not null default '',
+	brand_name text not null default '',
+	car_model text not null default '',
+	clientid text not null default '',
+	metrics_type text not null default '',
+	client_ip text not null default '',
+	client_type text not null default '',
+	client_company_name text not null default '',
+	client_name text not null default '',
+	client_email text not null default '',
+	client_phone_number text not null default '',
+	commentary text not null default '',
+	agreement_mailing text not null default '',
+	action_type text not null default '',
+	modification text not null default '',
+	mod_family text not null default '',
+	mod_body_type text not null default '',
+	mod_engine text not null default '',
+	mod_base text not null default '',
+	mod_tuning text not null default '',
+	vin text not null default '',
+	price integer not null default 0,
+	url_mod text not null default ''
+);
+
+create index if not exists forms_request_id_idx on forms (request_id);
+
+create table if not exists gazcrm_lead_get (
+	event_datetime text not null default '',
+	event_name text not null default '',
+	request_id text not null default '',
+	subdivisions_id text not null default '',
+	subdivisions_name text not null default '',
+	form_name text not null default '',
+	host_name text not null default '',
+	division text not null default '',
+	area text not null default '',
+	brand_name text not null default '',
+	clientid text not null default '',
+	metrics_type text not null default ''
+);
+
+create index if not exists gazcrm_lead_get_request_id_idx on gazcrm_lead_get (request_id);
+
+create table if not exists gazcrm_work_list (
+	event_datetime text not null default '',
+	event_name text not null default '',
+	gazcrm_client_id text not null default '',
+	gazcrm_worklist_id text not null default ''
+);
+
+create index if not exists gazcrm_work_list_client_id_idx on gazcrm_work_list (gazcrm_client_id);
+
+create table if not exists gazcrm_statuses (
+	event_datetime text not null default '',
+	event_name text not null default '',
+	request_id text not null default '',
+	gazcrm_client_id text not null default '',
+	gazcrm_worklist_id text not null default '',
+	clientid text not null default '',
+	metrics_type text not null default ''
+);
+
+create index if not exists gazcrm_statuses_request_id_idx on gazcrm_statuses (request_id);
+create index if not exists gazcrm_statuses_client_id_idx on gazcrm_statuses (gazcrm_client_id);
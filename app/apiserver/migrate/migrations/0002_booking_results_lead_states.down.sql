@@ -0,0 +1,3 @@
+drop table lead_transitions;
+drop table lead_states;
+drop table booking_results;
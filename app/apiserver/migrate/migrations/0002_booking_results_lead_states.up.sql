@@ -0,0 +1,30 @@
+create table booking_results (
+	request_id text not null,
+	status_ms text not null default '',
+	response_ms text not null default '',
+	status_gcrm text not null default '',
+	response_gcrm text not null default '',
+	created_at timestamptz not null default now()
+);
+
+create index booking_results_request_id_idx on booking_results (request_id, created_at);
+
+create table lead_states (
+	request_id text primary key,
+	state text not null,
+	event_datetime text not null default '',
+	updated_at timestamptz not null default now()
+);
+
+create table lead_transitions (
+	request_id text not null,
+	from_state text not null default '',
+	to_state text not null default '',
+	event_name text not null default '',
+	event_datetime text not null default '',
+	accepted boolean not null,
+	reason text not null default '',
+	created_at timestamptz not null default now()
+);
+
+create index lead_transitions_request_id_idx on lead_transitions (request_id);
@@ -0,0 +1,3 @@
+drop table bills;
+drop table payments;
+drop table mailing_statuses;
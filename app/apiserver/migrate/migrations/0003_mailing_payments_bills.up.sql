@@ -0,0 +1,34 @@
+create table mailing_statuses (
+	request_id text not null,
+	client_email text not null default '',
+	action_type text not null default '',
+	brand_name text not null default '',
+	status text not null,
+	attempts integer not null default 0,
+	error text not null default '',
+	created_at timestamptz not null default now()
+);
+
+create index mailing_statuses_request_id_idx on mailing_statuses (request_id);
+
+create table payments (
+	payment_id text primary key,
+	request_id text not null,
+	amount bigint not null,
+	currency text not null,
+	status text not null,
+	provider text not null,
+	provider_payment_id text not null default '',
+	payment_url text not null default '',
+	created_at timestamptz not null default now(),
+	updated_at timestamptz not null default now()
+);
+
+create index payments_request_id_idx on payments (request_id);
+
+create table bills (
+	request_id text primary key,
+	bill_number text not null default '',
+	pdf bytea not null,
+	created_at timestamptz not null default now()
+);
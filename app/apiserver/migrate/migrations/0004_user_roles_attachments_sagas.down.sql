@@ -0,0 +1,6 @@
+drop table booking_cancellations;
+drop table booking_saga_steps;
+drop table booking_sagas;
+drop table booking_attachments;
+drop table attachments;
+alter table users drop column role;
@@ -0,0 +1,46 @@
+alter table users add column role text not null default 'client';
+
+create table attachments (
+	attachment_id text primary key,
+	user_id bigint not null,
+	file_name text not null default '',
+	content_type text not null,
+	size bigint not null,
+	sha256 text not null,
+	created_at timestamptz not null default now()
+);
+
+create table booking_attachments (
+	request_id text not null,
+	attachment_id text not null references attachments (attachment_id),
+	primary key (request_id, attachment_id)
+);
+
+create table booking_sagas (
+	request_id text primary key,
+	state text not null,
+	user_id bigint not null default 0,
+	created_at timestamptz not null default now(),
+	updated_at timestamptz not null default now()
+);
+
+create index booking_sagas_state_idx on booking_sagas (state, updated_at);
+
+create table booking_saga_steps (
+	request_id text not null,
+	step text not null,
+	status text not null,
+	error text not null default '',
+	created_at timestamptz not null default now()
+);
+
+create index booking_saga_steps_request_id_idx on booking_saga_steps (request_id, created_at);
+
+create table booking_cancellations (
+	request_id text not null,
+	user_id bigint not null,
+	reason text not null default '',
+	created_at timestamptz not null default now()
+);
+
+create index booking_cancellations_request_id_idx on booking_cancellations (request_id);
@@ -0,0 +1,6 @@
+drop table audit_log;
+drop function audit_log_append_only();
+alter table users
+	drop column created_at,
+	drop column last_login,
+	drop column disabled;
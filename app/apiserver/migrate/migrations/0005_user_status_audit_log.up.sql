@@ -0,0 +1,31 @@
+alter table users
+	add column disabled boolean not null default false,
+	add column last_login timestamptz,
+	add column created_at timestamptz not null default now();
+
+create table audit_log (
+	id bigserial primary key,
+	user_id bigint not null default 0,
+	action text not null default '',
+	route text not null default '',
+	request_id text not null default '',
+	client_ip text not null default '',
+	outcome text not null,
+	payload_hash text not null default '',
+	details text not null default '',
+	created_at timestamptz not null default now()
+);
+
+create index audit_log_user_id_idx on audit_log (user_id, created_at);
+create index audit_log_request_id_idx on audit_log (request_id);
+create index audit_log_created_at_idx on audit_log (created_at);
+
+-- append only
+create function audit_log_append_only() returns trigger language plpgsql as $$
+begin
+	raise exception 'audit_log is append only';
+end
+$$;
+
+create trigger audit_log_append_only before update or delete on audit_log
+	for each row execute function audit_log_append_only();
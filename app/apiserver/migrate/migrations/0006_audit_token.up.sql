@@ -0,0 +1 @@
+alter table audit_log add column token_hash text not null default '';
@@ -0,0 +1,2 @@
+alter table forms drop column testmod;
+alter table booking drop column testmod;
@@ -0,0 +1,2 @@
+alter table booking add column if not exists testmod boolean not null default false;
+alter table forms add column if not exists testmod boolean not null default false;
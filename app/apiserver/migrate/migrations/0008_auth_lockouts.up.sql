@@ -0,0 +1,7 @@
+create table auth_lockouts (
+	kind text not null,
+	key text not null,
+	until timestamptz not null,
+	lockouts integer not null,
+	primary key (kind, key)
+);
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+//audit outcomes
+const (
+	AuditOk    = "ok"
+	AuditError = "error"
+)
+
+//audit actions
+const (
+	AuditRequest      = "request" //authentication and data-changing api calls
+	AuditUserCreate   = "user_create"
+	AuditUserDisable  = "user_disable"
+	AuditUserEnable   = "user_enable"
+	AuditUserDelete   = "user_delete"
+	AuditUserPassword = "user_password"
+	AuditUserRole     = "user_role"
+	AuditAuthUnlock   = "auth_unlock"
+)
+
+//audit log query, zero values are not filtered
+type AuditFilter struct {
+	UserId    *uint64
+	RequestId string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+//audit log record, append only
+type AuditEvent struct {
+	UserId      uint64    `json:"user_id"` //acting user, 0 - cli
+	Action      string    `json:"action"`
+	Route       string    `json:"route"`
+	RequestId   string    `json:"request_id"`
+	ClientIP    string    `json:"client_ip"`
+	Outcome     string    `json:"outcome"`
+	PayloadHash string    `json:"payload_hash"`
+	TokenHash   string    `json:"token_hash"`
+	Details     string    `json:"details"`
+	TimeEvent   time.Time `json:"event_datetime"`
+}
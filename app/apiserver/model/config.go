@@ -37,8 +37,22 @@ type Service struct {
 			LifeTerm    int    `yaml:"term"`
 		} `yaml:"jwt"`
 		Client struct {
-			UrlGazCrmTest     string `yaml:"url_gaz_crm_test"`
-			UrlMailingService string `yaml:"url_mailing_service"`
+			UrlGazCrmTest      string `yaml:"url_gaz_crm_test"`
+			UserGazCrmTest     string `yaml:"user_gaz_crm_test"`
+			PasswordGazCrmTest string `yaml:"password_gaz_crm_test"`
+			UrlGazCrm          string `yaml:"url_gaz_crm"` //prod, required
+			UserGazCrm         string `yaml:"user_gaz_crm"`
+			PasswordGazCrm     string `yaml:"password_gaz_crm"`
+			UrlMailingService  string `yaml:"url_mailing_service"`
+			//lead events of paid and cancelled bookings, empty - not sent
+			UrlGazCrmEventsTest string `yaml:"url_gaz_crm_events_test"`
+			UrlGazCrmEvents     string `yaml:"url_gaz_crm_events"`
+			//gaz crm client, zero values take defaults
+			GazCrmTimeout         int  `yaml:"gaz_crm_timeout"` //seconds per attempt
+			GazCrmRetries         *int `yaml:"gaz_crm_retries"` //unset - default, 0 - no retries
+			GazCrmBackoff         int  `yaml:"gaz_crm_backoff"` //milliseconds
+			GazCrmBreakerFailures int  `yaml:"gaz_crm_breaker_failures"`
+			GazCrmBreakerCooldown int  `yaml:"gaz_crm_breaker_cooldown"` //seconds
 		} `yaml:"client"`
 		Queryies struct {
 			Booking          string `yaml:"booking"`
@@ -52,10 +66,127 @@ type Service struct {
 			OptionsSprav     string `yaml:"options_sprav"`
 			Packets          string `yaml:"packets"`
 			Colors           string `yaml:"colors"`
+			BookingPaid      string `yaml:"booking_paid"`
+			BookingCancel    string `yaml:"booking_cancel"`
 		} `yaml:"queryies"`
+		Webhook struct {
+			Window       int `yaml:"window"` //seconds, timestamp tolerance and nonce lifetime, 0 - 300
+			Integrations []struct {
+				Name   string `yaml:"name"`
+				Secret string `yaml:"secret"`
+			} `yaml:"integrations"`
+		} `yaml:"webhook"`
+		Mailing struct {
+			Transport    string `yaml:"transport"` //http (url_mailing_service), smtp, memory
+			Workers      int    `yaml:"workers"`
+			QueueSize    int    `yaml:"queue_size"`
+			Retries      int    `yaml:"retries"`
+			Templates    string `yaml:"templates"` //dir with {action_type}_{brand}.html overrides
+			From         string `yaml:"from"`
+			SmtpAddr     string `yaml:"smtp_addr"`
+			SmtpUser     string `yaml:"smtp_user"`
+			SmtpPassword string `yaml:"smtp_password"`
+		} `yaml:"mailing"`
+		Payment struct {
+			Provider string `yaml:"provider"` //empty - payment routes disabled, fake - dev only
+			Secret   string `yaml:"secret"`   //required with provider, callback signature secret
+			PayUrl   string `yaml:"pay_url"`  //fake provider pay page
+			Dev      bool   `yaml:"dev"`      //test or dev config, allows fake provider
+		} `yaml:"payment"`
+		Bill struct {
+			Font     string `yaml:"font"`     //truetype font with cyrillic
+			Template string `yaml:"template"` //empty - default
+			Vat      int    `yaml:"vat"`      //percent, 0 - 20
+			Seller   struct {
+				Name        string `yaml:"name"`
+				Inn         string `yaml:"inn"`
+				Kpp         string `yaml:"kpp"`
+				Address     string `yaml:"address"`
+				Bank        string `yaml:"bank"`
+				Bik         string `yaml:"bik"`
+				Account     string `yaml:"account"`
+				CorrAccount string `yaml:"corr_account"`
+			} `yaml:"seller"`
+		} `yaml:"bill"`
+		//failures are counted per instance, lockouts are persisted, unlock by admin api or users cli
+		AuthLimits struct {
+			IpAttempts    int `yaml:"ip_attempts"`    //failed attempts before lockout, 0 - 20
+			EmailAttempts int `yaml:"email_attempts"` //0 - 5
+			Window        int `yaml:"window"`         //seconds of counted attempts, 0 - 900
+			Lockout       int `yaml:"lockout"`        //seconds of first lockout, doubles, 0 - 60
+			MaxLockout    int `yaml:"max_lockout"`    //seconds, 0 - 3600
+		} `yaml:"auth_limits"`
+		//client ip of auth limits and audit, remote address if proxies are not set
+		Proxies struct {
+			Trusted []string `yaml:"trusted"` //ip or cidr of reverse proxies, their X-Forwarded-For is used
+		} `yaml:"proxies"`
+		Cors struct {
+			Groups []CorsGroup `yaml:"groups"` //first group of path prefix applies
+		} `yaml:"cors"`
+		Compression struct {
+			Disabled   bool `yaml:"disabled"`
+			MinSize    int  `yaml:"min_size"`    //bytes, smaller responses are not compressed, 0 - 1024
+			Level      int  `yaml:"level"`       //gzip level 1-9, 0 - default
+			CatalogTtl int  `yaml:"catalog_ttl"` //seconds of cached catalog responses, 0 - not cached
+		} `yaml:"compression"`
+		Body struct {
+			MaxSize int64 `yaml:"max_size"` //bytes, 0 - 1 MB
+			Strict  bool  `yaml:"strict"`   //reject unknown json fields
+			Routes  []struct {
+				Path    string `yaml:"path"`     //route template
+				MaxSize int64  `yaml:"max_size"` //0 - default
+				Strict  bool   `yaml:"strict"`
+			} `yaml:"routes"`
+		} `yaml:"body"`
+		RateLimits struct {
+			Rate   float64              `yaml:"rate"`  //requests per second per user, 0 - no limit
+			Burst  int                  `yaml:"burst"` //bucket capacity
+			Ttl    int                  `yaml:"ttl"`   //seconds of idle refilled bucket before eviction, 0 - 600
+			Roles  map[string]RateLimit `yaml:"roles"` //per role, instead of default
+			Routes []struct {
+				Path      string `yaml:"path"` //route template, /auth/bookings/{request_id}/payment
+				Role      string `yaml:"role"` //optional
+				RateLimit `yaml:",inline"`
+			} `yaml:"routes"` //route buckets are separate from default bucket
+		} `yaml:"rate_limits"`
+		Attachments struct {
+			MaxSize     int64  `yaml:"max_size"` //bytes, 0 - 10 MB
+			Storage     string `yaml:"storage"`  //local, s3
+			Dir         string `yaml:"dir"`      //local storage, empty - attachment routes disabled
+			S3Endpoint  string `yaml:"s3_endpoint"`
+			S3Region    string `yaml:"s3_region"`
+			S3Bucket    string `yaml:"s3_bucket"`
+			S3AccessKey string `yaml:"s3_access_key"`
+			S3SecretKey string `yaml:"s3_secret_key"`
+			ClamdAddr   string `yaml:"clamd_addr"` //empty - no virus scan
+		} `yaml:"attachments"`
+		Saga struct {
+			GazCrmRequired bool `yaml:"gazcrm_required"` //cancel reservation if gaz crm delivery fails, otherwise booking is kept with status 400
+			Timeout        int  `yaml:"timeout"`         //seconds, running sagas older are recovered
+		} `yaml:"saga"`
+		Lead struct {
+			Events map[string]string `yaml:"events"` //gaz crm status event_name: lead state, unmapped - unknown_event
+		} `yaml:"lead"`
 	} `yaml:"spec"`
 }
 
+//rate limit of route or role
+type RateLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+//cors of route group
+type CorsGroup struct {
+	Prefix      string   `yaml:"prefix"`      //path prefix, /auth/get
+	Origins     []string `yaml:"origins"`     //* - any origin, not with credentials
+	Methods     []string `yaml:"methods"`     //empty - GET, POST
+	Headers     []string `yaml:"headers"`     //request headers, empty - Authorization, Content-Type
+	Expose      []string `yaml:"expose"`      //response headers readable by browser
+	Credentials bool     `yaml:"credentials"` //cookies and auth headers
+	MaxAge      int      `yaml:"max_age"`     //seconds of preflight cache
+}
+
 //New config
 func NewConfig() (*Service, error) {
 
@@ -2,52 +2,54 @@ package model
 
 import (
 	"database/sql/driver"
+	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
 //Data booking
 type DataBooking struct {
-	RequestId             string `json:"request_id"`
-	ActionType            string `json:"action_type"`
-	UniqModCode           int    `json:"uniq_mod_code"`
-	Modification          string `json:"modification"`
-	ModFamily             string `json:"mod_family"`
-	ModBodyType           string `json:"mod_body_type"`
-	ModEngine             string `json:"mod_engine"`
-	ModBase               string `json:"mod_base"`
-	ModTuning             string `json:"mod_tuning"`
-	Vin                   string `json:"vin"`
-	PriceWithNds          int    `json:"price"`
-	TypeClient            string `json:"client_type"`
-	Inn                   string `json:"inn"`
-	Kpp                   string `json:"kpp"`
-	Ogrn                  string `json:"ogrn"`
-	YurAddressCode        string `json:"reg_address_code"`
-	DeliveryAddressCode   string `json:"delivery_address_code"`
-	DeliveryAddress       string `json:"delivery_address"`
-	Hid                   string `json:"hid"`
-	CompanyName           string `json:"client_company_name"`
-	RepresentativeName    string `json:"representative_name"`
-	RepresentativeSurname string `json:"representative_surname"`
-	Surname               string `json:"surname"`
-	Name                  string `json:"client_name"`
-	Patronymic            string `json:"patronymic"`
-	PassportSer           string `json:"passport_ser"`
-	PassportNumber        string `json:"passport_number"`
-	Snils                 string `json:"snils"`
-	DateOfBirth           string `json:"date_of_birth"`
-	Email                 string `json:"client_email"`
-	PhoneNumber           string `json:"client_phone_number"`
-	Comment               string `json:"commentary"`
-	Consentmailing        string `json:"agreement_mailing"`
-	TimeRequest           string `json:"event_datetime"`
-	File                  string `json:"file"`
-	BillNumber            string `json:"bill_namber"`
-	UrlMod                string `json:"url_mod"`
-	Clientid              string `json:"clientid_google"` //Google Analytics cookies
-	Ymuid                 string `json:"ClientID"`        //Yandex Metrics cookies
-	TestMod               bool   `json:"testmod"`         //true - test, false - prod
+	RequestId             string   `json:"request_id"`
+	ActionType            string   `json:"action_type"`
+	UniqModCode           int      `json:"uniq_mod_code"`
+	Modification          string   `json:"modification"`
+	ModFamily             string   `json:"mod_family"`
+	ModBodyType           string   `json:"mod_body_type"`
+	ModEngine             string   `json:"mod_engine"`
+	ModBase               string   `json:"mod_base"`
+	ModTuning             string   `json:"mod_tuning"`
+	Vin                   string   `json:"vin"`
+	PriceWithNds          int      `json:"price"`
+	TypeClient            string   `json:"client_type"`
+	Inn                   string   `json:"inn"`
+	Kpp                   string   `json:"kpp"`
+	Ogrn                  string   `json:"ogrn"`
+	YurAddressCode        string   `json:"reg_address_code"`
+	DeliveryAddressCode   string   `json:"delivery_address_code"`
+	DeliveryAddress       string   `json:"delivery_address"`
+	Hid                   string   `json:"hid"`
+	CompanyName           string   `json:"client_company_name"`
+	RepresentativeName    string   `json:"representative_name"`
+	RepresentativeSurname string   `json:"representative_surname"`
+	Surname               string   `json:"surname"`
+	Name                  string   `json:"client_name"`
+	Patronymic            string   `json:"patronymic"`
+	PassportSer           string   `json:"passport_ser"`
+	PassportNumber        string   `json:"passport_number"`
+	Snils                 string   `json:"snils"`
+	DateOfBirth           string   `json:"date_of_birth"`
+	Email                 string   `json:"client_email"`
+	PhoneNumber           string   `json:"client_phone_number"`
+	Comment               string   `json:"commentary"`
+	Consentmailing        string   `json:"agreement_mailing"`
+	TimeRequest           string   `json:"event_datetime"`
+	File                  string   `json:"file"`        //deprecated, inline file, use attachments
+	Attachments           []string `json:"attachments"` //uploaded attachment ids
+	BillNumber            string   `json:"bill_namber"`
+	UrlMod                string   `json:"url_mod"`
+	Clientid              string   `json:"clientid_google"` //Google Analytics cookies
+	Ymuid                 string   `json:"ClientID"`        //Yandex Metrics cookies
+	TestMod               bool     `json:"testmod"`         //true - test, false - prod
 	//fields for gaz crm
 	SubdivisionsId   string `json:"subdivisions_id"`
 	SubdivisionsName string `json:"subdivisions_name"`
@@ -63,9 +65,9 @@ type DataBooking struct {
 }
 
 //Validation data booking
+//common rules plus rule sets by client_type and action_type (rules.go)
 func (d *DataBooking) ValidateDataBooking() error {
-	return validation.ValidateStruct(
-		d,
+	rules := []*validation.FieldRules{
 		validation.Field(&d.RequestId, validation.Required),
 		validation.Field(&d.UniqModCode, validation.Required),
 		validation.Field(&d.Modification, validation.Required),
@@ -74,35 +76,42 @@ func (d *DataBooking) ValidateDataBooking() error {
 		validation.Field(&d.ModEngine, validation.Required),
 		validation.Field(&d.ModBase, validation.Required),
 		validation.Field(&d.ModTuning, validation.Required),
-		validation.Field(&d.Vin, validation.Required),
+		validation.Field(&d.Vin, validation.Required, RuleVin),
 		validation.Field(&d.PriceWithNds, validation.Required),
-		validation.Field(&d.TypeClient, validation.In("company", "personal")),
-		validation.Field(&d.Inn, validation.Required),
-		validation.Field(&d.Kpp, validation.Required),
-		validation.Field(&d.Ogrn, validation.Required),
-		validation.Field(&d.YurAddressCode, validation.Required),
+		validation.Field(&d.TypeClient, validation.Required, validation.In("company", "personal")),
 		validation.Field(&d.DeliveryAddressCode, validation.Required),
 		validation.Field(&d.Hid, validation.Required),
-		validation.Field(&d.CompanyName, validation.Required),
-		validation.Field(&d.Surname, validation.Required),
-		validation.Field(&d.Name, validation.Required),
-		validation.Field(&d.Patronymic, validation.Required),
-		validation.Field(&d.DateOfBirth, validation.Date("2006-01-02")),
-		validation.Field(&d.PassportSer, validation.Required),
-		validation.Field(&d.PassportNumber, validation.Required),
-		validation.Field(&d.Email, validation.Required),
-		validation.Field(&d.PhoneNumber, validation.Required),
+		validation.Field(&d.Email, validation.Required, RuleEmail),
+		validation.Field(&d.PhoneNumber, validation.Required, RulePhone),
 		validation.Field(&d.TimeRequest, validation.Date("2006-01-02T15:04:05")),
-		validation.Field(&d.Patronymic, validation.Required),
 		validation.Field(&d.Consentmailing, validation.In("yes", "no")),
 		//validation fo gaz crm fields
 		validation.Field(&d.Division, validation.In("lcv/mcv", "bus")),
 		validation.Field(&d.Area, validation.In("dealer", "distrib")),
 		validation.Field(&d.MetricsType, validation.In("yandex")),
-		validation.Field(&d.TypeClient, validation.In("company", "personal")),
-		validation.Field(&d.TimeRequest, validation.Date("2006-01-02T15:04:05")),
 		validation.Field(&d.ActionType, validation.Required, validation.In("form", "bill", "acquiring")),
-	)
+	}
+
+	if f, ok := bookingClientRules[d.TypeClient]; ok {
+		rules = append(rules, f(d)...)
+	}
+	if f, ok := bookingActionRules[d.ActionType]; ok {
+		rules = append(rules, f(d)...)
+	}
+
+	return validation.ValidateStruct(d, rules...)
+}
+
+//Normalize data booking before validation
+func (d *DataBooking) Normalize() {
+	d.Vin = strings.ToUpper(strings.TrimSpace(d.Vin))
+	d.Kpp = strings.ToUpper(strings.TrimSpace(d.Kpp))
+	d.PassportSer = strings.ReplaceAll(strings.TrimSpace(d.PassportSer), " ", "")
+	d.PassportNumber = strings.TrimSpace(d.PassportNumber)
+	d.Email = strings.TrimSpace(d.Email)
+	if phone, err := NormalizePhone(d.PhoneNumber); err == nil {
+		d.PhoneNumber = phone
+	}
 }
 
 //sql null handling
@@ -324,6 +333,7 @@ type DataForms struct {
 	Vin          string `json:"vin"`
 	PriceWithNds int    `json:"price"`
 	UrlMod       string `json:"url_mod"`
+	TestMod      bool   `json:"testmod"` //true - test, false - prod
 }
 
 //Validation data fiz
@@ -341,8 +351,8 @@ func (d *DataForms) ValidateDataForms() error {
 		validation.Field(&d.Clientid, validation.Required),
 		validation.Field(&d.MetricsType, validation.Required),
 		validation.Field(&d.Name, validation.Required),
-		validation.Field(&d.Email, validation.Required),
-		validation.Field(&d.PhoneNumber, validation.Required),
+		validation.Field(&d.Email, validation.Required, RuleEmail),
+		validation.Field(&d.PhoneNumber, validation.Required, RulePhone),
 		validation.Field(&d.UrlMod, validation.Required),
 		validation.Field(&d.Modification, validation.Required),
 		validation.Field(&d.ModFamily, validation.Required),
@@ -362,6 +372,15 @@ func (d *DataForms) ValidateDataForms() error {
 	)
 }
 
+//Normalize data forms before validation
+func (d *DataForms) Normalize() {
+	d.Vin = strings.ToUpper(strings.TrimSpace(d.Vin))
+	d.Email = strings.TrimSpace(d.Email)
+	if phone, err := NormalizePhone(d.PhoneNumber); err == nil {
+		d.PhoneNumber = phone
+	}
+}
+
 //gaz crm
 //data struct for call gaz crm api method
 type DataGazCrm struct {
@@ -434,6 +453,33 @@ type DataGazCrmReq struct {
 	AgreementMailing struct {
 		AgreementMailing string `json:"agreement_mailing"` //general field with booking
 	}
+	TestMod struct {
+		TestMod bool `json:"testmod"` //general field with booking
+	}
+}
+
+//event of existing lead for gaz crm: booking paid, cancelled
+type DataGazCrmEvent struct {
+	Data DataGazCrmEventReq `json:"Data"`
+}
+
+//event of existing lead for gaz crm
+type DataGazCrmEventReq struct {
+	TimeRequest struct {
+		TimeRequest string `json:"event_datetime"`
+	}
+	EventName struct {
+		EventName string `json:"event_name"`
+	}
+	RequestId struct {
+		RequestId string `json:"request_id"`
+	}
+	Commentary struct {
+		Commentary string `json:"commentary"`
+	}
+	TestMod struct {
+		TestMod bool `json:"testmod"`
+	}
 }
 
 //data struct for call gaz crm api method
@@ -538,3 +584,73 @@ type ResponseGazCrm struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
 }
+
+//booking status lookup
+type BookingStatus struct {
+	RequestId string         `json:"request_id"`
+	Booking   *DataBooking   `json:"booking,omitempty"`
+	Form      *DataForms     `json:"form,omitempty"`
+	Result    *BookingResult `json:"result,omitempty"`
+	LeadState *LeadState     `json:"lead_state,omitempty"`
+	Saga      *BookingSaga   `json:"saga,omitempty"`
+	Timeline  []BookingEvent `json:"timeline"`
+}
+
+//stored result of mssql and gaz crm calls
+type BookingResult struct {
+	StatusMs       string `json:"status_ms"`
+	ResponseMs     string `json:"response_ms"`
+	StatusGazCrm   string `json:"status_gcrm"`
+	ResponseGazCrm string `json:"response_gcrm"`
+	TimeResult     string `json:"result_datetime"`
+}
+
+//gaz crm event of booking timeline
+type BookingEvent struct {
+	TimeEvent        string `json:"event_datetime"`
+	Source           string `json:"source"` //lead_get, status, work_list
+	EventName        string `json:"event_name"`
+	GazcrmClientId   string `json:"gazcrm_client_id,omitempty"`
+	GazCrmWorkListId string `json:"gazcrm_worklist_id,omitempty"`
+}
+
+//mailing delivery status
+type MailingStatus struct {
+	RequestId  string `json:"request_id"`
+	Email      string `json:"client_email"`
+	ActionType string `json:"action_type"`
+	BrandName  string `json:"brand_name"`
+	Status     string `json:"status"` //sent, failed, skipped
+	Attempts   int    `json:"attempts"`
+	Error      string `json:"error,omitempty"`
+}
+
+//payment statuses
+const (
+	PaymentPending  = "pending"
+	PaymentPaid     = "paid"
+	PaymentFailed   = "failed"
+	PaymentCanceled = "canceled"
+)
+
+//payment of booking (action_type acquiring)
+type Payment struct {
+	PaymentId         string `json:"payment_id"`
+	RequestId         string `json:"request_id"`
+	Amount            int64  `json:"amount"` //kopecks
+	Currency          string `json:"currency"`
+	Status            string `json:"status"`
+	Provider          string `json:"provider"`
+	ProviderPaymentId string `json:"provider_payment_id"`
+	PaymentUrl        string `json:"payment_url"`
+}
+
+//attachment file
+type Attachment struct {
+	Id          string `json:"id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Sha256      string `json:"sha256"`
+	UserId      uint64 `json:"-"`
+}
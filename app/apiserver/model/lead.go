@@ -0,0 +1,126 @@
+package model
+
+import "time"
+
+//lead states
+const (
+	LeadCreated    = "created"     //booking or form stored
+	LeadDelivered  = "delivered"   //accepted by gaz crm
+	LeadInWork     = "in_work"     //taken into work list
+	LeadProcessing = "processing"  //status changes in work
+	LeadClosed     = "closed"      //closed without result
+	LeadWon        = "closed_won"  //sale
+	LeadLost       = "closed_lost" //refusal
+)
+
+//transition rejection reasons
+const (
+	LeadOutOfOrder        = "out_of_order"
+	LeadInvalidTransition = "invalid_transition"
+	LeadUnknownEvent      = "unknown_event"
+)
+
+//gaz crm event sources
+const (
+	LeadSourceSite     = "site"
+	LeadSourceLeadGet  = "lead_get"
+	LeadSourceWorkList = "work_list"
+	LeadSourceStatus   = "status"
+)
+
+//allowed transitions, closed states are final
+var leadTransitions = map[string][]string{
+	"":             {LeadCreated, LeadDelivered},
+	LeadCreated:    {LeadDelivered, LeadInWork, LeadClosed, LeadLost},
+	LeadDelivered:  {LeadDelivered, LeadInWork, LeadProcessing, LeadClosed, LeadWon, LeadLost},
+	LeadInWork:     {LeadInWork, LeadProcessing, LeadClosed, LeadWon, LeadLost},
+	LeadProcessing: {LeadInWork, LeadProcessing, LeadClosed, LeadWon, LeadLost},
+}
+
+//current lead state
+type LeadState struct {
+	RequestId string `json:"request_id"`
+	State     string `json:"state"`
+	TimeEvent string `json:"event_datetime"`
+}
+
+//incoming lead event
+type LeadEvent struct {
+	Source    string
+	EventName string
+	TimeEvent string
+}
+
+//lead transition, rejected transitions are stored with reason
+type LeadTransition struct {
+	RequestId string `json:"request_id"`
+	FromState string `json:"from_state"`
+	ToState   string `json:"to_state"`
+	EventName string `json:"event_name"`
+	TimeEvent string `json:"event_datetime"`
+	Accepted  bool   `json:"accepted"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+//state of event: lead_get and work_list by source, statuses by event name (config spec.lead.events)
+func LeadEventState(event LeadEvent, events map[string]string) string {
+	switch event.Source {
+	case LeadSourceSite:
+		return event.EventName
+	case LeadSourceLeadGet:
+		return LeadDelivered
+	case LeadSourceWorkList:
+		return LeadInWork
+	}
+	if state, ok := events[event.EventName]; ok {
+		return state
+	}
+	return ""
+}
+
+//apply event to current state (nil if lead has no state yet)
+func ApplyLeadEvent(requestId string, current *LeadState, event LeadEvent, events map[string]string) *LeadTransition {
+
+	t := &LeadTransition{
+		RequestId: requestId,
+		ToState:   LeadEventState(event, events),
+		EventName: event.EventName,
+		TimeEvent: event.TimeEvent,
+	}
+	if current != nil {
+		t.FromState = current.State
+	}
+
+	if t.ToState == "" {
+		t.Reason = LeadUnknownEvent
+		return t
+	}
+
+	if current != nil && leadEventBefore(event.TimeEvent, current.TimeEvent) {
+		t.Reason = LeadOutOfOrder
+		return t
+	}
+
+	for _, s := range leadTransitions[t.FromState] {
+		if s == t.ToState {
+			t.Accepted = true
+			return t
+		}
+	}
+
+	t.Reason = LeadInvalidTransition
+	return t
+}
+
+//event datetime order, unparsable datetimes are not ordered
+func leadEventBefore(a string, b string) bool {
+	ta, err := time.Parse("2006-01-02T15:04:05", a)
+	if err != nil {
+		return false
+	}
+	tb, err := time.Parse("2006-01-02T15:04:05", b)
+	if err != nil {
+		return false
+	}
+	return ta.Before(tb)
+}
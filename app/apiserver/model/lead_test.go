@@ -0,0 +1,88 @@
+package model
+
+import "testing"
+
+func TestApplyLeadEvent(t *testing.T) {
+
+	events := map[string]string{
+		"status_in_progress": LeadProcessing,
+		"status_closed":      LeadClosed,
+		"status_won":         LeadWon,
+		"status_lost":        LeadLost,
+	}
+	const at = "2026-01-02T10:00:00"
+	state := func(s string) *LeadState {
+		return &LeadState{RequestId: "r1", State: s, TimeEvent: at}
+	}
+
+	tests := []struct {
+		name     string
+		current  *LeadState
+		event    LeadEvent
+		to       string
+		accepted bool
+		reason   string
+	}{
+		{"created", nil, LeadEvent{LeadSourceSite, LeadCreated, at}, LeadCreated, true, ""},
+		{"delivered without created", nil, LeadEvent{LeadSourceSite, LeadDelivered, at}, LeadDelivered, true, ""},
+		{"lead get", state(LeadCreated), LeadEvent{LeadSourceLeadGet, "lead_get", at}, LeadDelivered, true, ""},
+		{"repeated lead get", state(LeadDelivered), LeadEvent{LeadSourceLeadGet, "lead_get", at}, LeadDelivered, true, ""},
+		{"work list", state(LeadDelivered), LeadEvent{LeadSourceWorkList, "work_list", at}, LeadInWork, true, ""},
+		{"status in work", state(LeadInWork), LeadEvent{LeadSourceStatus, "status_in_progress", at}, LeadProcessing, true, ""},
+		{"back to work list", state(LeadProcessing), LeadEvent{LeadSourceWorkList, "work_list", at}, LeadInWork, true, ""},
+		{"won", state(LeadProcessing), LeadEvent{LeadSourceStatus, "status_won", "2026-01-02T11:00:00"}, LeadWon, true, ""},
+		{"lost before delivery", state(LeadCreated), LeadEvent{LeadSourceStatus, "status_lost", at}, LeadLost, true, ""},
+		{"same datetime is in order", state(LeadInWork), LeadEvent{LeadSourceStatus, "status_closed", at}, LeadClosed, true, ""},
+		{"unparsable datetime is in order", state(LeadInWork), LeadEvent{LeadSourceStatus, "status_closed", "02.01.2026"}, LeadClosed, true, ""},
+		//rejected
+		{"unknown status", state(LeadInWork), LeadEvent{LeadSourceStatus, "status_unknown", at}, "", false, LeadUnknownEvent},
+		{"unknown status of new lead", nil, LeadEvent{LeadSourceStatus, "status_unknown", at}, "", false, LeadUnknownEvent},
+		{"out of order", state(LeadInWork), LeadEvent{LeadSourceStatus, "status_won", "2026-01-02T09:59:59"}, LeadWon, false, LeadOutOfOrder},
+		{"out of order work list", state(LeadProcessing), LeadEvent{LeadSourceWorkList, "work_list", "2026-01-01T10:00:00"}, LeadInWork, false, LeadOutOfOrder},
+		{"created twice", state(LeadCreated), LeadEvent{LeadSourceSite, LeadCreated, at}, LeadCreated, false, LeadInvalidTransition},
+		{"delivered after work", state(LeadInWork), LeadEvent{LeadSourceLeadGet, "lead_get", at}, LeadDelivered, false, LeadInvalidTransition},
+		{"work without delivery status", nil, LeadEvent{LeadSourceWorkList, "work_list", at}, LeadInWork, false, LeadInvalidTransition},
+		{"won before delivery", state(LeadCreated), LeadEvent{LeadSourceStatus, "status_won", at}, LeadWon, false, LeadInvalidTransition},
+		{"closed to work", state(LeadClosed), LeadEvent{LeadSourceWorkList, "work_list", at}, LeadInWork, false, LeadInvalidTransition},
+		{"closed to processing", state(LeadClosed), LeadEvent{LeadSourceStatus, "status_in_progress", at}, LeadProcessing, false, LeadInvalidTransition},
+		{"won to lost", state(LeadWon), LeadEvent{LeadSourceStatus, "status_lost", at}, LeadLost, false, LeadInvalidTransition},
+		{"lost to delivered", state(LeadLost), LeadEvent{LeadSourceLeadGet, "lead_get", at}, LeadDelivered, false, LeadInvalidTransition},
+		{"closed twice", state(LeadClosed), LeadEvent{LeadSourceStatus, "status_closed", at}, LeadClosed, false, LeadInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		got := ApplyLeadEvent("r1", tt.current, tt.event, events)
+		from := ""
+		if tt.current != nil {
+			from = tt.current.State
+		}
+		if got.RequestId != "r1" || got.FromState != from || got.ToState != tt.to || got.Accepted != tt.accepted || got.Reason != tt.reason {
+			t.Errorf("%s: %+v, want %s -> %s accepted %v reason %q", tt.name, got, from, tt.to, tt.accepted, tt.reason)
+		}
+		if got.EventName != tt.event.EventName || got.TimeEvent != tt.event.TimeEvent {
+			t.Errorf("%s: event %s %s", tt.name, got.EventName, got.TimeEvent)
+		}
+	}
+}
+
+func TestLeadEventState(t *testing.T) {
+
+	events := map[string]string{"status_won": LeadWon}
+
+	tests := map[LeadEvent]string{
+		{LeadSourceSite, LeadCreated, ""}:      LeadCreated,
+		{LeadSourceLeadGet, "anything", ""}:    LeadDelivered,
+		{LeadSourceWorkList, "anything", ""}:   LeadInWork,
+		{LeadSourceStatus, "status_won", ""}:   LeadWon,
+		{LeadSourceStatus, "status_lost", ""}:  "",
+		{LeadSourceStatus, LeadSourceSite, ""}: "",
+	}
+	for event, want := range tests {
+		if got := LeadEventState(event, events); got != want {
+			t.Errorf("%+v: %q, want %q", event, got, want)
+		}
+	}
+	if got := LeadEventState(LeadEvent{LeadSourceStatus, "status_won", ""}, nil); got != "" {
+		t.Errorf("no events config: %q", got)
+	}
+}
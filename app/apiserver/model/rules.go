@@ -0,0 +1,54 @@
+package model
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+//booking rule set
+type bookingRuleSet func(d *DataBooking) []*validation.FieldRules
+
+//booking rules by client_type
+var bookingClientRules = map[string]bookingRuleSet{
+	//legal entity: requisites and registration address
+	"company": func(d *DataBooking) []*validation.FieldRules {
+		return []*validation.FieldRules{
+			validation.Field(&d.Inn, validation.Required, RuleInnCompany),
+			validation.Field(&d.Kpp, validation.Required, RuleKpp),
+			validation.Field(&d.Ogrn, validation.Required, RuleOgrn),
+			validation.Field(&d.CompanyName, validation.Required),
+			validation.Field(&d.YurAddressCode, validation.Required),
+		}
+	},
+	//individual: passport data and date of birth, inn and ogrnip for entrepreneurs
+	"personal": func(d *DataBooking) []*validation.FieldRules {
+		return []*validation.FieldRules{
+			validation.Field(&d.Surname, validation.Required),
+			validation.Field(&d.Name, validation.Required),
+			validation.Field(&d.DateOfBirth, validation.Required, validation.Date("2006-01-02")),
+			validation.Field(&d.PassportSer, validation.Required, RulePassportSer),
+			validation.Field(&d.PassportNumber, validation.Required, RulePassportNumber),
+			validation.Field(&d.Snils, RuleSnils),
+			validation.Field(&d.Inn, RuleInnPersonal),
+			validation.Field(&d.Ogrn, RuleOgrnip),
+		}
+	},
+}
+
+//booking rules by action_type
+var bookingActionRules = map[string]bookingRuleSet{
+	"form": func(d *DataBooking) []*validation.FieldRules {
+		return nil
+	},
+	//bill is issued by number from the site
+	"bill": func(d *DataBooking) []*validation.FieldRules {
+		return []*validation.FieldRules{
+			validation.Field(&d.BillNumber, validation.Required),
+		}
+	},
+	//payment amount is the price
+	"acquiring": func(d *DataBooking) []*validation.FieldRules {
+		return []*validation.FieldRules{
+			validation.Field(&d.PriceWithNds, validation.Required, validation.Min(1)),
+		}
+	},
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+//booking with common fields only
+func ruleBooking(clientType string, actionType string) DataBooking {
+	return DataBooking{
+		RequestId:           "r1",
+		ActionType:          actionType,
+		UniqModCode:         1,
+		Modification:        "A21R33",
+		ModFamily:           "ГАЗель NEXT",
+		ModBodyType:         "борт",
+		ModEngine:           "cummins",
+		ModBase:             "base",
+		ModTuning:           "none",
+		Vin:                 "X96A21R33K2000001",
+		PriceWithNds:        2500000,
+		TypeClient:          clientType,
+		DeliveryAddressCode: "52",
+		Hid:                 "h1",
+		Email:               "client@example.ru",
+		PhoneNumber:         "+79991234567",
+	}
+}
+
+//fields with errors, sorted
+func ruleErrors(t *testing.T, err error) string {
+	if err == nil {
+		return ""
+	}
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	fields := make([]string, 0, len(errs))
+	for f := range errs {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ",")
+}
+
+func TestBookingRuleSelection(t *testing.T) {
+
+	company := func(d *DataBooking) {
+		d.Inn, d.Kpp, d.Ogrn = "7707083893", "773601001", "1027700132195"
+		d.CompanyName, d.YurAddressCode = "ООО Ромашка", "77"
+	}
+	personal := func(d *DataBooking) {
+		d.Surname, d.Name, d.DateOfBirth = "Иванов", "Иван", "1980-01-02"
+		d.PassportSer, d.PassportNumber = "4506", "123456"
+	}
+
+	tests := []struct {
+		client string
+		action string
+		fill   func(d *DataBooking)
+		want   string
+	}{
+		//missing client rule sets
+		{"company", "form", nil, "client_company_name,inn,kpp,ogrn,reg_address_code"},
+		{"personal", "form", nil, "client_name,date_of_birth,passport_number,passport_ser,surname"},
+		//complete client data
+		{"company", "form", company, ""},
+		{"personal", "form", personal, ""},
+		//action rule sets
+		{"company", "bill", company, "bill_namber"},
+		{"personal", "bill", personal, "bill_namber"},
+		{"company", "acquiring", company, ""},
+		{"personal", "acquiring", func(d *DataBooking) { personal(d); d.PriceWithNds = -1 }, "price"},
+		//requisites of other client type are not required
+		{"personal", "form", func(d *DataBooking) { personal(d); d.Inn = "7707083893" }, "inn"},
+		{"personal", "form", func(d *DataBooking) { personal(d); d.Inn, d.Ogrn = "500100732259", "304500116000157" }, ""},
+		{"company", "form", func(d *DataBooking) { company(d); d.Inn = "500100732259" }, "inn"},
+		//unknown types select no rule set
+		{"other", "form", nil, "client_type"},
+		{"company", "other", company, "action_type"},
+	}
+
+	for _, tt := range tests {
+		d := ruleBooking(tt.client, tt.action)
+		if tt.fill != nil {
+			tt.fill(&d)
+		}
+		if got := ruleErrors(t, d.ValidateDataBooking()); got != tt.want {
+			t.Errorf("%s/%s: errors %q, want %q", tt.client, tt.action, got, tt.want)
+		}
+	}
+}
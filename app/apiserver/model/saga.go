@@ -0,0 +1,69 @@
+package model
+
+//booking saga states
+const (
+	SagaRunning            = "running"
+	SagaRecovering         = "recovering" //claimed by recovery of one instance
+	SagaCompleted          = "completed"
+	SagaFailed             = "failed"              //required step failed before reservation
+	SagaCompensated        = "compensated"         //reservation cancelled
+	SagaCompensationFailed = "compensation_failed" //reservation may remain, manual action needed
+	SagaCancelled          = "cancelled"           //cancelled by client or admin
+)
+
+//booking saga steps
+const (
+	SagaStepStore   = "store_postgres"
+	SagaStepReserve = "reserve_mssql"
+	SagaStepDeliver = "deliver_gazcrm"
+	SagaStepCancel  = "cancel_mssql" //compensation of reserve
+)
+
+//saga step statuses
+const (
+	SagaStepDone  = "done"
+	SagaStepError = "error"
+)
+
+//booking saga progress
+type BookingSaga struct {
+	RequestId string     `json:"request_id"`
+	State     string     `json:"state"`
+	UserId    uint64     `json:"-"` //originating client
+	Steps     []SagaStep `json:"steps"`
+}
+
+//saga step result
+type SagaStep struct {
+	Step      string `json:"step"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	TimeEvent string `json:"event_datetime"`
+}
+
+//step is done
+func (s *BookingSaga) Done(step string) bool {
+	for _, st := range s.Steps {
+		if st.Step == step && st.Status == SagaStepDone {
+			return true
+		}
+	}
+	return false
+}
+
+//step was attempted
+func (s *BookingSaga) Attempted(step string) bool {
+	for _, st := range s.Steps {
+		if st.Step == step {
+			return true
+		}
+	}
+	return false
+}
+
+//booking cancellation
+type Cancellation struct {
+	RequestId string `json:"request_id"`
+	Reason    string `json:"reason"`
+	UserId    uint64 `json:"-"`
+}
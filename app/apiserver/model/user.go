@@ -1,6 +1,16 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+//user roles
+const (
+	RoleClient = "client"
+	RoleAdmin  = "admin"
+)
 
 //User
 type User1 struct {
@@ -9,6 +19,27 @@ type User1 struct {
 	Password string `json:"password"`
 }
 
+//api user
+type User struct {
+	Id        uint64     `json:"id"`
+	Email     string     `json:"email"`
+	Password  string     `json:"password,omitempty"` //input only
+	Role      string     `json:"role"`
+	Disabled  bool       `json:"disabled"`
+	LastLogin *time.Time `json:"last_login"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+//validate new user
+func (u *User) Validate() error {
+	return validation.ValidateStruct(
+		u,
+		validation.Field(&u.Email, validation.Required, RuleEmail),
+		validation.Field(&u.Password, validation.Required, validation.Length(8, 0)),
+		validation.Field(&u.Role, validation.Required, validation.In(RoleClient, RoleAdmin)),
+	)
+}
+
 //for jwt verify
 type User2 struct {
 	UserID uint64
@@ -20,11 +51,6 @@ type Token_exp struct {
 	Exp   time.Time
 }
 
-type AccessDetails struct {
-	UserId uint64
-	Exp    uint64
-}
-
 //response struct
 type Response struct {
 	Status   string `json:"status"`
@@ -37,4 +63,19 @@ type ResponseBooking struct {
 	ResponseMs     string `json:"response_ms"`
 	StatusGazCrm   string `json:"status_gcrm"`
 	ResponseGazCrm string `json:"response_gcrm"`
+	State          string `json:"state,omitempty"` //booking saga state
+}
+
+//auth lockout kinds
+const (
+	LockoutIp    = "ip"
+	LockoutEmail = "email"
+)
+
+//auth lockout, persisted so it survives restart and can be lifted by cli
+type AuthLockout struct {
+	Kind     string    `json:"kind"`
+	Key      string    `json:"key"`
+	Until    time.Time `json:"until"`
+	Lockouts int       `json:"lockouts"`
 }
@@ -0,0 +1,205 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+//errors
+var (
+	ErrPhoneNumber = errors.New("must be a valid phone number")
+)
+
+//business identifiers rules
+var (
+	RuleInnCompany     = validation.NewStringRule(isInnCompany, "must be a valid 10-digit INN")
+	RuleInnPersonal    = validation.NewStringRule(isInnPersonal, "must be a valid 12-digit INN")
+	RuleKpp            = validation.NewStringRule(isKpp, "must be a valid KPP")
+	RuleOgrn           = validation.NewStringRule(isOgrn, "must be a valid 13-digit OGRN")
+	RuleOgrnip         = validation.NewStringRule(isOgrnip, "must be a valid 15-digit OGRNIP")
+	RuleSnils          = validation.NewStringRule(isSnils, "must be a valid SNILS")
+	RulePassportSer    = validation.NewStringRule(isPassportSer, "must be 4 digits")
+	RulePassportNumber = validation.NewStringRule(isPassportNumber, "must be 6 digits")
+	RuleVin            = validation.NewStringRule(isVin, "must be a valid VIN")
+	RulePhone          = validation.NewStringRule(isPhone, ErrPhoneNumber.Error())
+	RuleEmail          = validation.NewStringRule(isEmail, "must be a valid email address")
+)
+
+var (
+	reKpp   = regexp.MustCompile(`^\d{4}[\dA-Z]{2}\d{3}$`)
+	reVin   = regexp.MustCompile(`^[A-HJ-NPR-Z0-9]{17}$`)
+	reEmail = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+)
+
+//vin transliteration and weights (ISO 3779)
+var (
+	vinValues  = map[rune]int{'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8, 'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9, 'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9}
+	vinWeights = []int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+)
+
+//digits of string, nil if any rune is not a digit
+func digits(s string) []int {
+	d := make([]int, 0, len(s))
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return nil
+		}
+		d = append(d, int(c-'0'))
+	}
+	return d
+}
+
+//weighted sum of digits
+func weightedSum(d []int, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += d[i] * w
+	}
+	return sum
+}
+
+//inn legal entity, 10 digits
+func isInnCompany(s string) bool {
+	d := digits(s)
+	if len(d) != 10 {
+		return false
+	}
+	return weightedSum(d, []int{2, 4, 10, 3, 5, 9, 4, 6, 8})%11%10 == d[9]
+}
+
+//inn individual, 12 digits
+func isInnPersonal(s string) bool {
+	d := digits(s)
+	if len(d) != 12 {
+		return false
+	}
+	n11 := weightedSum(d, []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) % 11 % 10
+	n12 := weightedSum(d, []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) % 11 % 10
+	return n11 == d[10] && n12 == d[11]
+}
+
+//kpp, 9 chars: tax office code, reason code, number
+func isKpp(s string) bool {
+	return reKpp.MatchString(s)
+}
+
+//ogrn style checksum: number without last digit mod divisor, last digit
+func ogrnChecksum(d []int, divisor int) bool {
+	rem := 0
+	for _, v := range d[:len(d)-1] {
+		rem = (rem*10 + v) % divisor
+	}
+	return rem%10 == d[len(d)-1]
+}
+
+//ogrn legal entity, 13 digits
+func isOgrn(s string) bool {
+	d := digits(s)
+	if len(d) != 13 {
+		return false
+	}
+	return ogrnChecksum(d, 11)
+}
+
+//ogrnip individual entrepreneur, 15 digits
+func isOgrnip(s string) bool {
+	d := digits(s)
+	if len(d) != 15 {
+		return false
+	}
+	return ogrnChecksum(d, 13)
+}
+
+//snils, 11 digits, separators allowed (123-456-789 01)
+func isSnils(s string) bool {
+	d := digits(strings.NewReplacer("-", "", " ", "").Replace(s))
+	if len(d) != 11 {
+		return false
+	}
+	sum := weightedSum(d, []int{9, 8, 7, 6, 5, 4, 3, 2, 1})
+	check := sum
+	if sum > 101 {
+		check = sum % 101
+	}
+	if check == 100 || check == 101 {
+		check = 0
+	}
+	return check == d[9]*10+d[10]
+}
+
+//passport series, 4 digits, space is stripped by normalization (45 06)
+func isPassportSer(s string) bool {
+	return len(digits(s)) == 4
+}
+
+//passport number, 6 digits
+func isPassportNumber(s string) bool {
+	return len(digits(s)) == 6
+}
+
+//vin, 17 chars without I, O, Q
+//check digit is mandatory for north american vins only (wmi 1-5)
+func isVin(s string) bool {
+	if !reVin.MatchString(s) {
+		return false
+	}
+	if s[0] < '1' || s[0] > '5' {
+		return true
+	}
+	sum := 0
+	for i, c := range s {
+		v, ok := vinValues[c]
+		if !ok {
+			v = int(c - '0')
+		}
+		sum += v * vinWeights[i]
+	}
+	check := byte('0' + sum%11)
+	if sum%11 == 10 {
+		check = 'X'
+	}
+	return s[8] == check
+}
+
+func isPhone(s string) bool {
+	_, err := NormalizePhone(s)
+	return err == nil
+}
+
+func isEmail(s string) bool {
+	return reEmail.MatchString(s)
+}
+
+//normalize phone number to E.164, russian numbers without country code are completed with +7
+func NormalizePhone(s string) (string, error) {
+
+	s = strings.TrimSpace(s)
+	plus := strings.HasPrefix(s, "+")
+	s = strings.TrimPrefix(s, "+")
+
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case c == ' ', c == '-', c == '(', c == ')':
+		default:
+			return "", ErrPhoneNumber
+		}
+	}
+	d := b.String()
+
+	switch {
+	case plus && len(d) >= 8 && len(d) <= 15 && d[0] != '0':
+		return "+" + d, nil
+	case !plus && len(d) == 11 && (d[0] == '8' || d[0] == '7'):
+		return "+7" + d[1:], nil
+	case !plus && len(d) == 10 && d[0] == '9':
+		return "+7" + d, nil
+	}
+
+	return "", ErrPhoneNumber
+}
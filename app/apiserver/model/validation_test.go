@@ -0,0 +1,82 @@
+package model
+
+import "testing"
+
+func TestBusinessIdentifiers(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		check func(string) bool
+		valid []string
+		wrong []string
+	}{
+		{"inn company", isInnCompany, []string{"7707083893"}, []string{"7707083894", "770708389", "77070838a3", ""}},
+		{"inn personal", isInnPersonal, []string{"500100732259"}, []string{"500100732258", "50010073225", ""}},
+		{"kpp", isKpp, []string{"773601001", "7736AB001"}, []string{"77360100", "7736ab001", "77360100A"}},
+		{"ogrn", isOgrn, []string{"1027700132195"}, []string{"1027700132196", "102770013219"}},
+		{"ogrnip", isOgrnip, []string{"304500116000157"}, []string{"304500116000158", "30450011600015"}},
+		{"snils", isSnils, []string{"112-233-445 95", "11223344595"}, []string{"112-233-445 96", "1122334459"}},
+		{"passport series", isPassportSer, []string{"4506"}, []string{"45 06", "450", "45a6"}},
+		{"passport number", isPassportNumber, []string{"123456"}, []string{"12345", "12345a"}},
+		{"vin", isVin, []string{"1M8GDM9AXKP042788", "X96A21R33K2000001"}, []string{"1M8GDM9A1KP042788", "X96A21R33K200000I", "X96A21R33K200000"}},
+		{"email", isEmail, []string{"a.b@example.ru"}, []string{"a@b", "ab.ru", "a b@c.ru"}},
+	}
+
+	for _, tt := range tests {
+		for _, v := range tt.valid {
+			if !tt.check(v) {
+				t.Errorf("%s: %q must be valid", tt.name, v)
+			}
+		}
+		for _, v := range tt.wrong {
+			if tt.check(v) {
+				t.Errorf("%s: %q must be invalid", tt.name, v)
+			}
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "+7 (999) 123-45-67", want: "+79991234567"},
+		{in: "8 999 123 45 67", want: "+79991234567"},
+		{in: "79991234567", want: "+79991234567"},
+		{in: "9991234567", want: "+79991234567"},
+		{in: " +375291234567 ", want: "+375291234567"},
+		{in: "(+7) 999 123 45 67", err: true},
+		{in: "-+79991234567", err: true},
+		{in: "+7999+1234567", err: true},
+		{in: "++79991234567", err: true},
+		{in: "+0123456789", err: true},
+		{in: "1234567", err: true},
+		{in: "8 999 abc 45 67", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeBookingPassport(t *testing.T) {
+
+	d := DataBooking{PassportSer: " 45 06 ", PassportNumber: " 123456 "}
+	d.Normalize()
+
+	if d.PassportSer != "4506" || d.PassportNumber != "123456" {
+		t.Errorf("got %q %q", d.PassportSer, d.PassportNumber)
+	}
+}
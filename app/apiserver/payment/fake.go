@@ -0,0 +1,93 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//signature header of fake provider callbacks
+const HeaderSignature = "X-Payment-Signature"
+
+//Fake provider for local runs and tests
+//sessions are created locally, callbacks are signed with hmac sha256 of body
+type Fake struct {
+	secret string
+	payUrl string
+}
+
+//NewFake provider
+func NewFake(secret string, payUrl string) *Fake {
+	if payUrl == "" {
+		payUrl = "https://pay.example.local/"
+	}
+	return &Fake{
+		secret: secret,
+		payUrl: payUrl,
+	}
+}
+
+//Name fake
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+//CreateSession payment url on fake pay page
+func (f *Fake) CreateSession(ctx context.Context, p *model.Payment) error {
+	p.ProviderPaymentId = "fake-" + p.PaymentId
+	p.PaymentUrl = strings.TrimRight(f.payUrl, "/") + "/" + p.ProviderPaymentId
+	return nil
+}
+
+//ParseCallback verify body signature
+func (f *Fake) ParseCallback(r *http.Request) (*Notification, error) {
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil || !hmac.Equal(sign, f.sign(body)) {
+		return nil, ErrSignature
+	}
+
+	n := &Notification{}
+	if err := json.Unmarshal(body, n); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+//Callback signed callback request, as the provider would send it
+func (f *Fake) Callback(url string, n Notification) (*http.Request, error) {
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, hex.EncodeToString(f.sign(body)))
+
+	return req, nil
+}
+
+func (f *Fake) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(f.secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
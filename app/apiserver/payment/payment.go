@@ -0,0 +1,63 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//errors
+var (
+	ErrSignature = errors.New("payment signature error")
+	ErrProvider  = errors.New("unknown payment provider")
+	ErrSecret    = errors.New("payment secret is not configured")
+	ErrFake      = errors.New("fake payment provider outside dev config")
+)
+
+//Provider payment provider
+type Provider interface {
+	//Name of provider, stored with payment
+	Name() string
+	//CreateSession registers payment at provider, fills provider id and payment url
+	CreateSession(context.Context, *model.Payment) error
+	//ParseCallback verifies provider signature and returns notification
+	ParseCallback(*http.Request) (*Notification, error)
+}
+
+//Notification of payment state from provider
+type Notification struct {
+	PaymentId         string `json:"payment_id"`
+	ProviderPaymentId string `json:"provider_payment_id"`
+	Status            string `json:"status"` //model.PaymentPaid, model.PaymentFailed, model.PaymentCanceled
+	Amount            int64  `json:"amount"` //kopecks
+}
+
+//New provider from config, nil if payment is not configured
+//callbacks are signed with a secret, fake provider is refused outside dev config
+func New(config *model.Service) (Provider, error) {
+	if config.Spec.Payment.Provider == "" {
+		return nil, nil
+	}
+	if config.Spec.Payment.Secret == "" {
+		return nil, ErrSecret
+	}
+	switch config.Spec.Payment.Provider {
+	case "fake":
+		if !config.Spec.Payment.Dev {
+			return nil, ErrFake
+		}
+		return NewFake(config.Spec.Payment.Secret, config.Spec.Payment.PayUrl), nil
+	}
+	return nil, ErrProvider
+}
+
+//NewId payment id
+func NewId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
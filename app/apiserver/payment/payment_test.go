@@ -0,0 +1,57 @@
+package payment
+
+import (
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+func TestNew(t *testing.T) {
+
+	tests := []struct {
+		provider string
+		secret   string
+		dev      bool
+		err      error
+		enabled  bool
+	}{
+		{"fake", "secret", true, nil, true},
+		{"fake", "secret", false, ErrFake, false},
+		{"", "secret", false, nil, false},
+		{"", "", false, nil, false},
+		{"other", "secret", true, ErrProvider, false},
+		{"fake", "", true, ErrSecret, false},
+	}
+
+	for _, tt := range tests {
+		config := &model.Service{}
+		config.Spec.Payment.Provider = tt.provider
+		config.Spec.Payment.Secret = tt.secret
+		config.Spec.Payment.Dev = tt.dev
+
+		p, err := New(config)
+		if err != tt.err || (p != nil) != tt.enabled {
+			t.Errorf("provider %q secret %q dev %v: %v, error %v, want %v", tt.provider, tt.secret, tt.dev, p, err, tt.err)
+		}
+	}
+}
+
+func TestFakeCallbackSignature(t *testing.T) {
+
+	f := NewFake("secret", "")
+	n := Notification{PaymentId: "p1", Status: model.PaymentPaid, Amount: 100}
+
+	r, err := f.Callback("http://localhost/payment/callback", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.ParseCallback(r)
+	if err != nil || *got != n {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	r, _ = NewFake("other", "").Callback("http://localhost/payment/callback", n)
+	if _, err := f.ParseCallback(r); err != ErrSignature {
+		t.Errorf("foreign signature: error %v, want ErrSignature", err)
+	}
+}
@@ -0,0 +1,208 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/gazcrm"
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/payment"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//errors
+var (
+	errPaymentAction   = errors.New("booking action_type is not acquiring")
+	errPaymentProvider = errors.New("payment provider error")
+	errPaymentNotFound = errors.New("payment not found")
+	errPaymentAmount   = errors.New("payment amount mismatch")
+	errPaymentStatus   = errors.New("payment status unknown")
+	errPaymentState    = errors.New("booking is not active")
+	errPaymentPaid     = errors.New("booking is already paid")
+)
+
+//responses
+var (
+	respPayment          = "payment status updated"
+	respPaymentProcessed = "payment already processed"
+)
+
+//handle create payment session of booking
+func (s *server) handleCreatePayment() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		requestId := mux.Vars(r)["request_id"]
+
+		status, err := s.store.Data().QueryBookingStatusPostgres(requestId)
+		if err == store.ErrRecordNotFound || (err == nil && status.Booking == nil) {
+			s.error(w, r, http.StatusNotFound, errBookingNotFound)
+			logger.ErrorLogger.Println(errBookingNotFound, requestId)
+			return
+		}
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		ok, err := s.canAccessBooking(status.Saga, userId(r))
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+		if !ok {
+			s.error(w, r, http.StatusForbidden, errBookingForbidden)
+			logger.ErrorLogger.Println(errBookingForbidden, requestId, userId(r))
+			return
+		}
+
+		if status.Booking.ActionType != "acquiring" {
+			s.error(w, r, http.StatusBadRequest, errPaymentAction)
+			logger.ErrorLogger.Println(errPaymentAction, requestId)
+			return
+		}
+
+		//reserved bookings only, cancelled and failed can't be paid
+		if status.Saga == nil || status.Saga.State != model.SagaCompleted {
+			s.error(w, r, http.StatusConflict, errPaymentState)
+			logger.ErrorLogger.Println(errPaymentState, requestId)
+			return
+		}
+
+		//one open session per booking, repeated requests get the pending one
+		payments, err := s.store.Data().QueryBookingPaymentsPostgres(requestId)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+		for _, p := range payments {
+			switch p.Status {
+			case model.PaymentPaid:
+				s.error(w, r, http.StatusConflict, errPaymentPaid)
+				logger.ErrorLogger.Println(errPaymentPaid, requestId)
+				return
+			case model.PaymentPending:
+				s.respond(w, r, http.StatusOK, p)
+				logger.InfoLogger.Println("payment session pending " + p.PaymentId)
+				return
+			}
+		}
+
+		p := &model.Payment{
+			PaymentId: payment.NewId(),
+			RequestId: requestId,
+			Amount:    int64(status.Booking.PriceWithNds) * 100,
+			Currency:  "RUB",
+			Status:    model.PaymentPending,
+			Provider:  s.payment.Name(),
+		}
+
+		if err := s.payment.CreateSession(r.Context(), p); err != nil {
+			s.error(w, r, http.StatusBadGateway, errPaymentProvider)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		if err := s.store.Data().QueryInsertPaymentPostgres(*p); err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, p)
+		logger.InfoLogger.Println("payment session created " + p.PaymentId)
+
+	}
+
+}
+
+//handle payment provider callback
+func (s *server) handlePaymentCallback() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		n, err := s.payment.ParseCallback(r)
+		if err != nil {
+			s.error(w, r, http.StatusUnauthorized, payment.ErrSignature)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		p, err := s.store.Data().QueryPaymentPostgres(n.PaymentId)
+		if err == store.ErrRecordNotFound {
+			s.error(w, r, http.StatusNotFound, errPaymentNotFound)
+			logger.ErrorLogger.Println(errPaymentNotFound, n.PaymentId)
+			return
+		}
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		if n.Amount != p.Amount {
+			s.error(w, r, http.StatusBadRequest, errPaymentAmount)
+			logger.ErrorLogger.Printf("payment %s: amount %d, expected %d", p.PaymentId, n.Amount, p.Amount)
+			return
+		}
+
+		switch n.Status {
+		case model.PaymentPaid, model.PaymentFailed, model.PaymentCanceled:
+		default:
+			s.error(w, r, http.StatusBadRequest, errPaymentStatus)
+			logger.ErrorLogger.Println(errPaymentStatus, n.Status)
+			return
+		}
+
+		updated, err := s.store.Data().QueryUpdatePaymentStatusPostgres(p.PaymentId, n.Status)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+		if !updated {
+			s.respond(w, r, http.StatusOK, newResponse("Ok", respPaymentProcessed))
+			logger.WarningLogger.Println("payment callback repeated " + p.PaymentId)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, newResponse("Ok", respPayment))
+		logger.InfoLogger.Printf("payment %s: %s", p.PaymentId, n.Status)
+
+		if n.Status == model.PaymentPaid {
+			s.notifyPaid(*p)
+		}
+
+	}
+
+}
+
+//notify mssql and gaz crm of paid reservation
+//gaz crm gets lead event, re-sending the booking would create a duplicate lead
+func (s *server) notifyPaid(p model.Payment) {
+
+	resp, err := s.store.Data().QueryPaidMssql(p)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+	} else {
+		logger.InfoLogger.Println("payment stored in mssql: " + resp)
+	}
+
+	status, err := s.store.Data().QueryBookingStatusPostgres(p.RequestId)
+	if err != nil || status.Booking == nil {
+		logger.ErrorLogger.Println("payment booking not found " + p.RequestId)
+		return
+	}
+	if _, err := s.gazCrm(status.Booking.TestMod).Event(context.Background(), p.RequestId, gazcrm.EventPaid, p.PaymentId); err != nil {
+		logger.ErrorLogger.Println(err)
+		return
+	}
+	logger.InfoLogger.Println("gazcrm payment event sent " + p.RequestId)
+
+}
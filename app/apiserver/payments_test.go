@@ -0,0 +1,90 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/payment"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/teststore"
+)
+
+func TestPaymentCallback(t *testing.T) {
+
+	ts := newTestServer(t)
+	owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+	ts.book(t, owner, "r1", "acquiring")
+
+	var p model.Payment
+	decodeBody(t, ts.do(t, "POST", "/auth/bookings/r1/payment", owner, nil), &p)
+
+	callback := func(n payment.Notification, secret string) int {
+		r, err := payment.NewFake(secret, "").Callback("/payment/callback", n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	paid := payment.Notification{PaymentId: p.PaymentId, Status: model.PaymentPaid, Amount: p.Amount}
+	tests := []struct {
+		name   string
+		n      payment.Notification
+		secret string
+		code   int
+	}{
+		{"foreign signature", paid, "other secret", http.StatusUnauthorized},
+		{"unknown payment", payment.Notification{PaymentId: "missing", Status: model.PaymentPaid}, "payment secret", http.StatusNotFound},
+		{"amount mismatch", payment.Notification{PaymentId: p.PaymentId, Status: model.PaymentPaid, Amount: 1}, "payment secret", http.StatusBadRequest},
+		{"paid", paid, "payment secret", http.StatusOK},
+		{"repeated", paid, "payment secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := callback(tt.n, tt.secret); code != tt.code {
+			t.Errorf("%s: code %d, want %d", tt.name, code, tt.code)
+		}
+	}
+
+	//mssql and gaz crm are notified once
+	paidCalls := 0
+	for _, c := range ts.store.Records().Calls {
+		if c.Procedure == teststore.ProcBookingPaid {
+			paidCalls++
+		}
+	}
+	if paidCalls != 1 {
+		t.Errorf("%d mssql paid calls, want 1", paidCalls)
+	}
+	if got, want := ts.events(), []string{"r1 booking_paid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("gaz crm events %v, want %v", got, want)
+	}
+}
+
+func TestPaymentNotConfigured(t *testing.T) {
+
+	ts := newTestServer(t)
+	ts.config.Spec.Payment.Provider = ""
+	s, err := newServer(ts.store, ts.config, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.server = s
+	owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+	ts.book(t, owner, "r1", "acquiring")
+
+	ts.check(t, []routeCase{
+		{"payment", "POST", "/auth/bookings/r1/payment", owner, nil, http.StatusNotFound},
+		{"callback", "POST", "/payment/callback", 0, nil, http.StatusNotFound},
+	})
+
+	//fake provider needs dev config
+	ts.config.Spec.Payment.Provider = "fake"
+	ts.config.Spec.Payment.Dev = false
+	if _, err := newServer(ts.store, ts.config, http.DefaultClient); err != payment.ErrFake {
+		t.Errorf("error %v, want ErrFake", err)
+	}
+}
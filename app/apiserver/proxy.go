@@ -0,0 +1,69 @@
+package apiserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+//trusted proxies of config, ip or cidr
+func parseProxies(trusted []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(trusted))
+	for _, p := range trusted {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %q: invalid ip", p)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %v", p, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+//ip is one of trusted proxies
+func (s *server) trustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range s.proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+//set remote address of request from trusted proxy to client of X-Forwarded-For,
+//addresses are taken from the right, first one not of trusted proxies is the client
+func (s *server) realIP(r *http.Request) {
+
+	if len(s.proxies) == 0 || !s.trustedProxy(clientIP(r)) {
+		return
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			return
+		}
+		if i > 0 && s.trustedProxy(hop) {
+			continue
+		}
+		r.RemoteAddr = net.JoinHostPort(hop, "0")
+		return
+	}
+}
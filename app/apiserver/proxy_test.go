@@ -0,0 +1,60 @@
+package apiserver
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+
+	proxies, err := parseProxies([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{proxies: proxies}
+
+	tests := []struct {
+		name   string
+		remote string
+		header []string
+		ip     string
+	}{
+		{"direct client", "198.51.100.7:1000", nil, "198.51.100.7"},
+		{"untrusted forwarded", "198.51.100.7:1000", []string{"203.0.113.5"}, "198.51.100.7"},
+		{"trusted proxy", "192.0.2.1:1000", []string{"203.0.113.5"}, "203.0.113.5"},
+		{"trusted ipv6 proxy", "[2001:db8::1]:1000", []string{"203.0.113.5"}, "203.0.113.5"},
+		{"proxy chain", "10.1.1.1:1000", []string{"203.0.113.5, 10.2.2.2"}, "203.0.113.5"},
+		{"spoofed left of client", "10.1.1.1:1000", []string{"1.1.1.1, 203.0.113.5, 10.2.2.2"}, "203.0.113.5"},
+		{"several headers", "10.1.1.1:1000", []string{"1.1.1.1", "203.0.113.5"}, "203.0.113.5"},
+		{"only proxies", "10.1.1.1:1000", []string{"10.3.3.3, 10.2.2.2"}, "10.3.3.3"},
+		{"no header", "10.1.1.1:1000", nil, "10.1.1.1"},
+		{"garbage", "10.1.1.1:1000", []string{"unknown"}, "10.1.1.1"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, h := range tt.header {
+			r.Header.Add("X-Forwarded-For", h)
+		}
+		s.realIP(r)
+		if got := clientIP(r); got != tt.ip {
+			t.Errorf("%s: client ip %s, want %s", tt.name, got, tt.ip)
+		}
+	}
+
+	//no proxies configured, header is ignored
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.1.1.1:1000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.5")
+	(&server{}).realIP(r)
+	if got := clientIP(r); got != "10.1.1.1" {
+		t.Errorf("without proxies: client ip %s", got)
+	}
+
+	for _, bad := range []string{"proxy.local", "10.0.0.0/33"} {
+		if _, err := parseProxies([]string{bad}); err == nil {
+			t.Errorf("%s: accepted", bad)
+		}
+	}
+}
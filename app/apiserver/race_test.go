@@ -0,0 +1,132 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//parallel requests must not share handler state, run with -race
+func TestParallelAuthAndBooking(t *testing.T) {
+
+	ts := newTestServer(t)
+
+	const clients = 24
+	ids := make([]uint64, clients)
+	for i := range ids {
+		ids[i] = ts.store.Users().Seed(fmt.Sprintf("client%d@example.ru", i), fmt.Sprintf("password%d", i), model.RoleClient)
+	}
+
+	//request from own address of client, so ip limits don't interfere
+	send := func(i int, method string, path string, token string, body interface{}) (*httptest.ResponseRecorder, error) {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r := httptest.NewRequest(method, path, bytes.NewReader(b))
+		r.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", i+1)
+		r.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, r)
+		return w, nil
+	}
+
+	//client logs in, wrong password of other client is sent at the same time,
+	//then books and reads own booking with issued token
+	client := func(i int) error {
+
+		email, password := fmt.Sprintf("client%d@example.ru", i), fmt.Sprintf("password%d", i)
+		var w *httptest.ResponseRecorder
+		for n := 0; n < 3; n++ {
+			wrong, err := send(i, "POST", "/authentication", "", map[string]string{"email": email, "password": "wrong"})
+			if err != nil {
+				return err
+			}
+			if wrong.Code != http.StatusUnauthorized {
+				return fmt.Errorf("wrong password: code %d", wrong.Code)
+			}
+			if w, err = send(i, "POST", "/authentication", "", map[string]string{"email": email, "password": password}); err != nil {
+				return err
+			}
+			if w.Code != http.StatusOK {
+				return fmt.Errorf("login: code %d: %s", w.Code, w.Body)
+			}
+		}
+		var token model.Token_exp
+		if err := json.NewDecoder(w.Body).Decode(&token); err != nil {
+			return err
+		}
+		claims, err := ts.tokens.Verify(token.Token)
+		if err != nil {
+			return err
+		}
+		if claims.UserId != ids[i] {
+			return fmt.Errorf("token of user %d, want %d", claims.UserId, ids[i])
+		}
+
+		requestId := fmt.Sprintf("r%d", i)
+		booking := validBooking(requestId, "form")
+		booking.BillNumber = "B-" + requestId
+		w, err = send(i, "POST", "/auth/requestbooking", token.Token, booking)
+		if err != nil {
+			return err
+		}
+		if w.Code != http.StatusOK {
+			return fmt.Errorf("booking: code %d: %s", w.Code, w.Body)
+		}
+		if w, err = send(i, "GET", "/auth/bookings/"+requestId, token.Token, nil); err != nil {
+			return err
+		}
+		if w.Code != http.StatusOK {
+			return fmt.Errorf("status: code %d: %s", w.Code, w.Body)
+		}
+		var status model.BookingStatus
+		if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+			return err
+		}
+		if status.Saga == nil || status.Saga.RequestId != requestId || status.Saga.State != model.SagaCompleted {
+			return fmt.Errorf("status %+v", status)
+		}
+
+		saga, err := ts.store.Data().QuerySagaPostgres(requestId)
+		if err != nil {
+			return err
+		}
+		if saga.UserId != ids[i] {
+			return fmt.Errorf("saga of user %d, want %d", saga.UserId, ids[i])
+		}
+		return nil
+	}
+
+	errs := make([]error, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client(i)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("client %d: %v", i, err)
+		}
+	}
+	if n := len(ts.store.Records().Bookings); n != clients {
+		t.Errorf("%d bookings stored, want %d", n, clients)
+	}
+	if ts.crm() != clients {
+		t.Errorf("%d gaz crm requests, want %d", ts.crm(), clients)
+	}
+}
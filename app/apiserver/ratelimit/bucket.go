@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Rate of bucket, requests per second with burst capacity
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+//Buckets token buckets by key with usage counters,
+//refilled buckets idle for ttl are evicted with their usage
+type Buckets struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	full     time.Time //bucket is refilled, zero - never
+	allowed  uint64
+	rejected uint64
+}
+
+//Usage of bucket
+type Usage struct {
+	Key      string  `json:"key"`
+	Tokens   float64 `json:"tokens"`
+	Allowed  uint64  `json:"allowed"`
+	Rejected uint64  `json:"rejected"`
+}
+
+//NewBuckets limiter
+func NewBuckets(ttl time.Duration) *Buckets {
+	return &Buckets{
+		ttl:     ttl,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+//Allow takes token from bucket of key, returns wait until next token if bucket is empty
+func (b *Buckets) Allow(key string, rate Rate, now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+
+	burst := float64(rate.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	e, ok := b.buckets[key]
+	if !ok {
+		e = &bucket{tokens: burst, last: now}
+		b.buckets[key] = e
+	}
+
+	if now.After(e.last) {
+		e.tokens = math.Min(burst, e.tokens+now.Sub(e.last).Seconds()*rate.PerSecond)
+		e.last = now
+	}
+
+	allowed := e.tokens >= 1
+	if allowed {
+		e.tokens--
+		e.allowed++
+	} else {
+		e.rejected++
+	}
+
+	if rate.PerSecond <= 0 {
+		e.full = time.Time{}
+		if !allowed {
+			return false, time.Hour
+		}
+		return true, 0
+	}
+	e.full = now.Add(time.Duration((burst - e.tokens) / rate.PerSecond * float64(time.Second)))
+	if !allowed {
+		return false, time.Duration((1 - e.tokens) / rate.PerSecond * float64(time.Second))
+	}
+	return true, 0
+}
+
+//drop refilled buckets idle for ttl, locked
+//evicted bucket is the same as new one, limits are not reset
+func (b *Buckets) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.ttl {
+		return
+	}
+	b.lastSweep = now
+	for k, e := range b.buckets {
+		if !e.full.IsZero() && !now.Before(e.full) && now.Sub(e.last) >= b.ttl {
+			delete(b.buckets, k)
+		}
+	}
+}
+
+//Usage of all buckets, ordered by key
+func (b *Buckets) Usage() []Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage := make([]Usage, 0, len(b.buckets))
+	for k, e := range b.buckets {
+		usage = append(usage, Usage{Key: k, Tokens: e.tokens, Allowed: e.allowed, Rejected: e.rejected})
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Key < usage[j].Key
+	})
+	return usage
+}
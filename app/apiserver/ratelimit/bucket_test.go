@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketsAllow(t *testing.T) {
+
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	b := NewBuckets(time.Minute)
+	rate := Rate{PerSecond: 2, Burst: 3}
+
+	//full bucket takes burst
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.Allow("k", rate, now); !ok {
+			t.Fatalf("burst request %d rejected", i)
+		}
+	}
+	ok, wait := b.Allow("k", rate, now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("empty bucket: %v wait %s, want 500ms", ok, wait)
+	}
+
+	//other key has own bucket
+	if ok, _ := b.Allow("other", rate, now); !ok {
+		t.Error("other key rejected")
+	}
+
+	//refill by rate
+	now = now.Add(250 * time.Millisecond)
+	if ok, wait := b.Allow("k", rate, now); ok || wait != 250*time.Millisecond {
+		t.Errorf("half token: %v wait %s, want 250ms", ok, wait)
+	}
+	now = now.Add(250 * time.Millisecond)
+	if ok, _ := b.Allow("k", rate, now); !ok {
+		t.Error("refilled token rejected")
+	}
+
+	//refill is capped by burst
+	now = now.Add(30 * time.Second)
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.Allow("k", rate, now); !ok {
+			t.Fatalf("request %d after idle rejected", i)
+		}
+	}
+	if ok, _ := b.Allow("k", rate, now); ok {
+		t.Error("bucket refilled over burst")
+	}
+
+	usage := b.Usage()
+	if len(usage) != 2 || usage[0].Key != "k" || usage[0].Allowed != 7 || usage[0].Rejected != 3 {
+		t.Errorf("usage %+v", usage)
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	b := NewBuckets(time.Minute)
+	slow := Rate{PerSecond: 0.001, Burst: 1}
+
+	b.Allow("fast", Rate{PerSecond: 1, Burst: 1}, now)
+	b.Allow("slow", slow, now)
+
+	//idle for ttl: refilled bucket is evicted, empty one is kept
+	now = now.Add(2 * time.Minute)
+	b.Allow("new", slow, now)
+	usage := b.Usage()
+	if len(usage) != 2 || usage[0].Key != "new" || usage[1].Key != "slow" {
+		t.Fatalf("usage after sweep %+v", usage)
+	}
+
+	//kept bucket is still limited
+	if ok, _ := b.Allow("slow", slow, now); ok {
+		t.Error("slow bucket reset by sweep")
+	}
+}
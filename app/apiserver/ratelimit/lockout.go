@@ -0,0 +1,229 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Lockout counts failed attempts per key, locks key after attempts within window,
+//lockout duration doubles with every lockout up to max
+type Lockout struct {
+	mu        sync.Mutex
+	attempts  int
+	window    time.Duration
+	base      time.Duration
+	max       time.Duration
+	entries   map[string]*lockEntry
+	total     uint64
+	lastSweep time.Time
+}
+
+type lockEntry struct {
+	failures int
+	pending  int //reserved attempts in progress
+	first    time.Time
+	last     time.Time
+	lockouts int
+	until    time.Time
+}
+
+//Lock active lockout
+type Lock struct {
+	Key      string    `json:"key"`
+	Until    time.Time `json:"until"`
+	Lockouts int       `json:"lockouts"`
+}
+
+//NewLockout limiter
+func NewLockout(attempts int, window time.Duration, base time.Duration, max time.Duration) *Lockout {
+	if max < base {
+		max = base
+	}
+	return &Lockout{
+		attempts: attempts,
+		window:   window,
+		base:     base,
+		max:      max,
+		entries:  make(map[string]*lockEntry),
+	}
+}
+
+//Check remaining lockout of key, 0 if not locked
+func (l *Lockout) Check(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok && now.Before(e.until) {
+		return e.until.Sub(now)
+	}
+	return 0
+}
+
+//Reserve attempt of key, remaining lockout if not reserved,
+//failures and attempts in progress together can't exceed attempts,
+//reserved attempt ends with Fail or Release
+func (l *Lockout) Reserve(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	e := l.entry(key, now)
+	if now.Before(e.until) {
+		return e.until.Sub(now)
+	}
+	if e.failures+e.pending >= l.attempts {
+		//budget is taken by attempts in progress
+		return l.base
+	}
+	e.pending++
+	e.last = now
+
+	return 0
+}
+
+//Release reserved attempt that didn't fail
+func (l *Lockout) Release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok && e.pending > 0 {
+		e.pending--
+	}
+}
+
+//Fail records failed attempt, reserved attempt is released,
+//returns lockout duration if key got locked
+func (l *Lockout) Fail(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	e := l.entry(key, now)
+	if e.pending > 0 {
+		e.pending--
+	}
+	e.failures++
+	e.last = now
+
+	if e.failures < l.attempts {
+		return 0
+	}
+
+	d := l.base << uint(e.lockouts)
+	if d > l.max || d <= 0 {
+		d = l.max
+	}
+	e.lockouts++
+	e.failures = 0
+	e.first = now
+	e.until = now.Add(d)
+	l.total++
+
+	return d
+}
+
+//Reset failures and lockouts of key after successful attempt,
+//other attempts in progress stay reserved
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clear(key)
+}
+
+//Unlock key, false if key was not locked
+func (l *Lockout) Unlock(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	l.clear(key)
+	return ok && now.Before(e.until)
+}
+
+//Locked active lockout of key at now
+func (l *Lockout) Locked(key string, now time.Time) (Lock, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[key]; ok && now.Before(e.until) {
+		return Lock{Key: key, Until: e.until, Lockouts: e.lockouts}, true
+	}
+	return Lock{}, false
+}
+
+//Restore lockout of key, e.g. persisted before restart
+func (l *Lockout) Restore(lock Lock, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.entry(lock.Key, now)
+	e.until = lock.Until
+	e.lockouts = lock.Lockouts
+	e.last = now
+}
+
+//Locks active at now, ordered by key
+func (l *Lockout) Locks(now time.Time) []Lock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	locks := []Lock{}
+	for k, e := range l.entries {
+		if now.Before(e.until) {
+			locks = append(locks, Lock{Key: k, Until: e.until, Lockouts: e.lockouts})
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].Key < locks[j].Key
+	})
+	return locks
+}
+
+//Total lockouts since start
+func (l *Lockout) Total() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.total
+}
+
+//entry of key, window of counted failures restarts after window, locked
+func (l *Lockout) entry(key string, now time.Time) *lockEntry {
+	e, ok := l.entries[key]
+	if !ok {
+		e = &lockEntry{first: now}
+		l.entries[key] = e
+	}
+	if now.Sub(e.first) > l.window {
+		e.failures = 0
+		e.first = now
+	}
+	return e
+}
+
+//drop failures and lockouts of key, keep attempts in progress, locked
+func (l *Lockout) clear(key string) {
+	if e, ok := l.entries[key]; ok && e.pending > 0 {
+		l.entries[key] = &lockEntry{pending: e.pending, last: e.last}
+		return
+	}
+	delete(l.entries, key)
+}
+
+//drop idle entries, locked
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for k, e := range l.entries {
+		//lockout history is kept for max after last activity
+		if e.pending == 0 && now.After(e.until) && now.Sub(e.last) > l.window+l.max {
+			delete(l.entries, k)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockoutReserve(t *testing.T) {
+
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	l := NewLockout(5, time.Minute, time.Second, time.Minute)
+
+	//parallel attempts get no more than attempts reservations
+	var reserved int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Reserve("k", now) == 0 {
+				atomic.AddInt32(&reserved, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 5 {
+		t.Fatalf("%d attempts reserved, want 5", reserved)
+	}
+
+	//released attempt is refunded, failed attempts lock
+	l.Release("k")
+	if d := l.Reserve("k", now); d != 0 {
+		t.Errorf("after release: lockout %s", d)
+	}
+	for i := 0; i < 4; i++ {
+		if d := l.Fail("k", now); d != 0 {
+			t.Errorf("fail %d: lockout %s", i, d)
+		}
+	}
+	if d := l.Fail("k", now); d != time.Second {
+		t.Errorf("last fail: lockout %s, want 1s", d)
+	}
+	if d := l.Reserve("k", now); d != time.Second {
+		t.Errorf("locked: %s, want 1s", d)
+	}
+
+	//second lockout doubles
+	now = now.Add(time.Second)
+	for i := 0; i < 5; i++ {
+		l.Reserve("k", now)
+		l.Fail("k", now)
+	}
+	if d := l.Check("k", now); d != 2*time.Second {
+		t.Errorf("second lockout %s, want 2s", d)
+	}
+
+	//unlock keeps attempts in progress
+	now = now.Add(2 * time.Second)
+	l.Reserve("k", now)
+	if !l.Unlock("k", now.Add(-time.Second)) {
+		t.Error("unlock of locked key")
+	}
+	for i := 0; i < 4; i++ {
+		if d := l.Reserve("k", now); d != 0 {
+			t.Errorf("after unlock %d: lockout %s", i, d)
+		}
+	}
+	if d := l.Reserve("k", now); d == 0 {
+		t.Error("attempt in progress is not counted after unlock")
+	}
+}
+
+func TestLockoutRestore(t *testing.T) {
+
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	l := NewLockout(5, time.Minute, time.Second, time.Minute)
+
+	l.Restore(Lock{Key: "k", Until: now.Add(30 * time.Second), Lockouts: 2}, now)
+	lock, ok := l.Locked("k", now)
+	if !ok || lock.Lockouts != 2 || !lock.Until.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("restored %+v %v", lock, ok)
+	}
+	if d := l.Reserve("k", now); d != 30*time.Second {
+		t.Errorf("reserve of restored lock: %s", d)
+	}
+
+	//next lockout continues doubling from restored count
+	now = now.Add(31 * time.Second)
+	if _, ok := l.Locked("k", now); ok {
+		t.Error("lock active after until")
+	}
+	for i := 0; i < 5; i++ {
+		l.Fail("k", now)
+	}
+	if lock, _ := l.Locked("k", now); lock.Lockouts != 3 || lock.Until.Sub(now) != 4*time.Second {
+		t.Errorf("lockout after restore %+v", lock)
+	}
+}
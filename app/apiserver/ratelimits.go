@@ -0,0 +1,106 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/ratelimit"
+)
+
+//errors
+var (
+	errRateLimit = errors.New("rate limit exceeded")
+)
+
+const rateLimitTtl = 600 * time.Second
+
+//buckets of config ttl
+func newRateLimits(config *model.Service) *ratelimit.Buckets {
+	ttl := time.Duration(config.Spec.RateLimits.Ttl) * time.Second
+	if ttl <= 0 {
+		ttl = rateLimitTtl
+	}
+	return ratelimit.NewBuckets(ttl)
+}
+
+//bucket rule of request
+type rateRule struct {
+	key  string
+	rate ratelimit.Rate
+}
+
+//set Retry-After, whole seconds rounded up
+func retryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int((d+time.Second-1)/time.Second)))
+}
+
+//rule of route and role: route and role, route, role, default
+//route rules have own bucket, role and default rules share bucket of user
+func (s *server) rateRule(path string, role string) (rateRule, bool) {
+	c := s.config.Spec.RateLimits
+
+	var route *model.RateLimit
+	for i := range c.Routes {
+		if c.Routes[i].Path != path {
+			continue
+		}
+		if c.Routes[i].Role == role && role != "" {
+			route = &c.Routes[i].RateLimit
+			break
+		}
+		if c.Routes[i].Role == "" && route == nil {
+			route = &c.Routes[i].RateLimit
+		}
+	}
+
+	limit := model.RateLimit{Rate: c.Rate, Burst: c.Burst}
+	key := ""
+	if route != nil {
+		limit, key = *route, " route:"+path
+	} else if l, ok := c.Roles[role]; ok {
+		limit = l
+	}
+
+	if limit.Rate <= 0 {
+		return rateRule{}, false
+	}
+	return rateRule{key: key, rate: ratelimit.Rate{PerSecond: limit.Rate, Burst: limit.Burst}}, true
+}
+
+//Middleware rate limit per user, after auth middleware
+func (s *server) middleWareRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		path := routePath(r)
+
+		id := userId(r)
+
+		rule, ok := s.rateRule(path, userRole(r))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed, wait := s.rateLimits.Allow("user:"+strconv.FormatUint(id, 10)+rule.key, rule.rate, time.Now()); !allowed {
+			retryAfter(w, wait)
+			s.error(w, r, http.StatusTooManyRequests, errRateLimit)
+			logger.ErrorLogger.Printf("rate limit exceeded: user %d route %s", id, path)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+
+	})
+}
+
+//handle rate limit usage counters
+func (s *server) handleRateLimits() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, http.StatusOK, s.rateLimits.Usage())
+	}
+}
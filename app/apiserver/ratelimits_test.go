@@ -0,0 +1,75 @@
+package apiserver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRateLimits(t *testing.T) {
+
+	ts := newTestServer(t)
+	client := ts.store.Users().Seed("client@example.ru", "password", model.RoleClient)
+	admin := ts.store.Users().Seed("admin@example.ru", "password", model.RoleAdmin)
+
+	//rates are low enough to get no refill during test
+	if err := yaml.Unmarshal([]byte(`
+rate: 0.001
+burst: 2
+roles:
+  admin: {rate: 0.001, burst: 4}
+routes:
+  - {path: /auth/getsprav, rate: 0.001, burst: 1}
+  - {path: /auth/getsprav, role: admin, rate: 0.001, burst: 3}
+`), &ts.config.Spec.RateLimits); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer(ts.store, ts.config, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.server = s
+
+	//requests of user to path until 429
+	allowed := func(userId uint64, path string) int {
+		for i := 0; i < 10; i++ {
+			w := ts.do(t, "GET", path, userId, nil)
+			if w.Code != http.StatusTooManyRequests {
+				continue
+			}
+			if ra := w.Header().Get("Retry-After"); ra != "1000" {
+				t.Errorf("%s: Retry-After %q, want 1000", path, ra)
+			}
+			return i
+		}
+		return -1
+	}
+
+	tests := []struct {
+		name   string
+		userId uint64
+		path   string
+		want   int
+	}{
+		{"route", client, "/auth/getsprav", 1},
+		{"route of role", admin, "/auth/getsprav", 3},
+		{"default", client, "/auth/bookings/req-1", 2},
+		{"default shared by paths", client, "/auth/bookings/req-2", 0},
+		{"role", admin, "/auth/bookings/req-1", 4},
+	}
+	for _, tt := range tests {
+		if got := allowed(tt.userId, tt.path); got != tt.want {
+			t.Errorf("%s: %d requests allowed, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	//role of disabled user is not loaded, auth fails before limits
+	if err := ts.store.User().UpdateUserDisabled(client, true); err != nil {
+		t.Fatal(err)
+	}
+	if w := ts.do(t, "GET", "/auth/bookings/req-1", client, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("disabled user: %d", w.Code)
+	}
+}
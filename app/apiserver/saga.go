@@ -0,0 +1,268 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//mssql procedures success response
+const respMssqlOk = "Обработка данных прошла успешно"
+
+//default saga recovery timeout
+const defaultSagaTimeout = 300 * time.Second
+
+//errors
+var (
+	errBookingExists = errors.New("booking already exists")
+)
+
+//cancellation reasons
+const (
+	cancelReasonGazCrm   = "gaz crm delivery failed"
+	cancelReasonMssql    = "mssql booking error"
+	cancelReasonRecovery = "booking interrupted"
+)
+
+//booking saga: store in postgres, reserve in mssql, deliver to gaz crm
+//progress is persisted per step, reservation is cancelled if a required step fails
+type bookingSaga struct {
+	s      *server
+	req    model.DataBooking
+	result *model.ResponseBooking
+}
+
+//record step result, step errors are not fatal for the saga itself
+func (b *bookingSaga) step(step string, err error) {
+	st := model.SagaStep{Step: step, Status: model.SagaStepDone}
+	if err != nil {
+		st.Status = model.SagaStepError
+		st.Error = err.Error()
+	}
+	if err := b.s.store.Data().QueryInsertSagaStepPostgres(b.req.RequestId, st); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+}
+
+//set final state
+func (b *bookingSaga) finish(state string) {
+	b.result.State = state
+	if err := b.s.store.Data().QueryUpdateSagaStatePostgres(b.req.RequestId, state); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+	logger.InfoLogger.Println("booking " + b.req.RequestId + " " + state)
+}
+
+//cancel mssql reservation
+func (b *bookingSaga) compensate(reason string) {
+	b.finish(b.s.cancelReservation(b.req, reason))
+}
+
+//cancel reservation in mssql with step record, returns saga state
+func (s *server) cancelReservation(req model.DataBooking, reason string) string {
+
+	resp, err := s.store.Data().QueryCancelMssql(req, reason)
+	if err == nil && resp != respMssqlOk {
+		err = errors.New(resp)
+	}
+
+	st := model.SagaStep{Step: model.SagaStepCancel, Status: model.SagaStepDone}
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		st.Status = model.SagaStepError
+		st.Error = err.Error()
+	}
+	if err := s.store.Data().QueryInsertSagaStepPostgres(req.RequestId, st); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+
+	if err != nil {
+		return model.SagaCompensationFailed
+	}
+	return model.SagaCompensated
+}
+
+//start booking saga, ErrRecordExists on repeated request id
+func (s *server) startBookingSaga(requestId string, userId uint64) error {
+	return s.store.Data().QueryInsertSagaPostgres(requestId, userId)
+}
+
+//run started booking saga, returns http status and result
+//ctx of request bounds gaz crm delivery
+func (s *server) runBookingSaga(ctx context.Context, req model.DataBooking) (int, *model.ResponseBooking) {
+
+	b := &bookingSaga{
+		s:      s,
+		req:    req,
+		result: newResponseBooking("", "", "", ""),
+	}
+
+	//store, nothing to compensate
+	err := s.store.Data().QueryInsertBookingPostgres(req)
+	b.step(model.SagaStepStore, err)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		b.result.ResponseMs = errPostgres.Error()
+		b.finish(model.SagaFailed)
+		return http.StatusInternalServerError, b.result
+	}
+	logger.InfoLogger.Println("sites booking data stored")
+
+	if len(req.Attachments) > 0 {
+		if err := s.store.Data().QueryInsertBookingAttachmentsPostgres(req.RequestId, req.Attachments); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+	}
+
+	s.applyLeadEvent(req.RequestId, model.LeadEvent{Source: model.LeadSourceSite, EventName: model.LeadCreated, TimeEvent: req.TimeRequest})
+
+	//reserve, call error may hide a reservation, so it is compensated
+	resp, err := s.store.Data().QueryInsertMssql(req)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		b.step(model.SagaStepReserve, err)
+		b.result.StatusMs = "Error"
+		b.result.ResponseMs = errMssql.Error()
+		b.compensate(cancelReasonMssql)
+		return http.StatusBadGateway, b.result
+	}
+	b.result.ResponseMs = resp
+	if resp != respMssqlOk {
+		logger.ErrorLogger.Println(resp)
+		b.step(model.SagaStepReserve, errors.New(resp))
+		b.result.StatusMs = "Error"
+		b.finish(model.SagaFailed)
+		return http.StatusBadRequest, b.result
+	}
+	b.step(model.SagaStepReserve, nil)
+	b.result.StatusMs = "Ok"
+	logger.InfoLogger.Println("data booking stored in mssql")
+
+	//deliver
+	respg, err := s.gazCrm(req.TestMod).Booking(ctx, req)
+	b.step(model.SagaStepDeliver, err)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		b.result.StatusGazCrm = "Error"
+		b.result.ResponseGazCrm = gazCrmMessage(respg, err)
+		if s.config.Spec.Saga.GazCrmRequired {
+			b.compensate(cancelReasonGazCrm)
+			return http.StatusBadGateway, b.result
+		}
+	} else {
+		logger.InfoLogger.Println("gazcrm booking data transfer success")
+		b.result.StatusGazCrm = "Ok"
+		b.result.ResponseGazCrm = respBooking
+		s.applyLeadEvent(req.RequestId, model.LeadEvent{Source: model.LeadSourceSite, EventName: model.LeadDelivered, TimeEvent: req.TimeRequest})
+	}
+
+	b.finish(model.SagaCompleted)
+
+	//not required delivery failed, booking stays reserved, status is kept for sites
+	if b.result.StatusGazCrm == "Error" {
+		return http.StatusBadRequest, b.result
+	}
+
+	return http.StatusOK, b.result
+}
+
+//recover sagas interrupted by restart or crash, started with server, stops with ctx
+func (s *server) recoverSagas(ctx context.Context) {
+
+	timeout := time.Duration(s.config.Spec.Saga.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultSagaTimeout
+	}
+
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		ids, err := s.store.Data().QueryClaimStaleSagasPostgres(timeout)
+		if err != nil {
+			logger.ErrorLogger.Println(err)
+			continue
+		}
+
+		for _, id := range ids {
+			s.recoverSaga(id)
+		}
+
+	}
+
+}
+
+//steps after completed saga: bill and client mailing
+func (s *server) completeBooking(req model.DataBooking) {
+
+	if req.ActionType == "bill" {
+		if _, err := s.storeBill(req); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+	}
+
+	s.mailing.Enqueue(req)
+
+}
+
+//finish claimed saga: delivered bookings complete, reserved are cancelled, others fail
+func (s *server) recoverSaga(requestId string) {
+
+	saga, err := s.store.Data().QuerySagaPostgres(requestId)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return
+	}
+	if saga.State != model.SagaRecovering {
+		logger.InfoLogger.Println("booking saga finished before recovery " + requestId)
+		return
+	}
+
+	state := model.SagaFailed
+	switch {
+	case saga.Done(model.SagaStepDeliver),
+		saga.Attempted(model.SagaStepDeliver) && !s.config.Spec.Saga.GazCrmRequired:
+		state = model.SagaCompleted
+	case saga.Attempted(model.SagaStepReserve):
+		status, err := s.store.Data().QueryBookingStatusPostgres(requestId)
+		if err != nil || status.Booking == nil {
+			logger.ErrorLogger.Println("saga booking not found " + requestId)
+			state = model.SagaCompensationFailed
+			break
+		}
+		state = s.cancelReservation(*status.Booking, cancelReasonRecovery)
+	}
+
+	//saga run may have finished after claim
+	ok, err := s.store.Data().QueryCompareSagaStatePostgres(requestId, model.SagaRecovering, state)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return
+	}
+	if !ok {
+		logger.InfoLogger.Println("booking saga finished before recovery " + requestId)
+		return
+	}
+	logger.InfoLogger.Println("booking saga recovered " + requestId + " " + state)
+
+	if state == model.SagaCompleted {
+		status, err := s.store.Data().QueryBookingStatusPostgres(requestId)
+		if err != nil || status.Booking == nil {
+			logger.ErrorLogger.Println("saga booking not found " + requestId)
+			return
+		}
+		s.completeBooking(*status.Booking)
+	}
+
+}
@@ -0,0 +1,152 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/gazcrm"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/teststore"
+)
+
+//interrupted saga of user with stored booking and given steps done
+func interruptedSaga(t *testing.T, ts *testServer, requestId string, userId uint64, steps ...string) {
+	if err := ts.startBookingSaga(requestId, userId); err != nil {
+		t.Fatal(err)
+	}
+	booking := model.DataBooking{RequestId: requestId, ActionType: "form", Email: "client@example.ru", Consentmailing: "yes"}
+	if err := ts.store.Data().QueryInsertBookingPostgres(booking); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if err := ts.store.Data().QueryInsertSagaStepPostgres(requestId, model.SagaStep{Step: step, Status: model.SagaStepDone}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func sagaState(t *testing.T, ts *testServer, requestId string) string {
+	saga, err := ts.store.Data().QuerySagaPostgres(requestId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saga.State
+}
+
+func TestRecoverSagas(t *testing.T) {
+
+	ts := newTestServer(t)
+	interruptedSaga(t, ts, "delivered", 1, model.SagaStepStore, model.SagaStepReserve, model.SagaStepDeliver)
+	interruptedSaga(t, ts, "reserved", 1, model.SagaStepStore, model.SagaStepReserve)
+	interruptedSaga(t, ts, "stored", 1, model.SagaStepStore)
+
+	ids, err := ts.store.Data().QueryClaimStaleSagasPostgres(0)
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("claimed %v, %v", ids, err)
+	}
+	//claimed sagas are not claimed again by another instance
+	if again, _ := ts.store.Data().QueryClaimStaleSagasPostgres(time.Hour); len(again) != 0 {
+		t.Errorf("claimed twice: %v", again)
+	}
+
+	for _, id := range ids {
+		ts.recoverSaga(id)
+	}
+
+	for id, want := range map[string]string{"delivered": model.SagaCompleted, "reserved": model.SagaCompensated, "stored": model.SagaFailed} {
+		if got := sagaState(t, ts, id); got != want {
+			t.Errorf("%s: state %s, want %s", id, got, want)
+		}
+	}
+
+	calls := ts.store.Records().Calls
+	if len(calls) != 1 || calls[0].Procedure != teststore.ProcBookingCancel || calls[0].RequestId != "reserved" {
+		t.Errorf("mssql calls %v, want cancel of reserved", calls)
+	}
+
+	//completed saga gets post-completion steps
+	mails := ts.flush(t)
+	if len(mails) != 1 || mails[0].Booking.RequestId != "delivered" {
+		t.Errorf("mails %v, want one of delivered", mails)
+	}
+}
+
+func TestRecoverSagaFinishedByRun(t *testing.T) {
+
+	ts := newTestServer(t)
+	interruptedSaga(t, ts, "r1", 1, model.SagaStepStore, model.SagaStepReserve)
+
+	if _, err := ts.store.Data().QueryClaimStaleSagasPostgres(0); err != nil {
+		t.Fatal(err)
+	}
+	//original run completes after claim
+	if err := ts.store.Data().QueryUpdateSagaStatePostgres("r1", model.SagaCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	ts.recoverSaga("r1")
+
+	if got := sagaState(t, ts, "r1"); got != model.SagaCompleted {
+		t.Errorf("state %s, recovery must not override finished run", got)
+	}
+	if calls := ts.store.Records().Calls; len(calls) != 0 {
+		t.Errorf("mssql calls %v, want none", calls)
+	}
+}
+
+func TestBookingGazCrmFailure(t *testing.T) {
+
+	crm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer crm.Close()
+
+	for _, required := range []bool{false, true} {
+		ts := newTestServer(t)
+		ts.config.Spec.Saga.GazCrmRequired = required
+		config := *ts.config
+		config.Spec.Client.UrlGazCrm = crm.URL
+		client, err := gazcrm.New(http.DefaultClient, false, &config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts.gazcrm = client
+		owner := ts.store.Users().Seed("owner@example.ru", "password", model.RoleClient)
+
+		//not required delivery keeps reservation with baseline status
+		code, state := http.StatusBadRequest, model.SagaCompleted
+		if required {
+			code, state = http.StatusBadGateway, model.SagaCompensated
+		}
+		var result model.ResponseBooking
+		w := ts.do(t, "POST", "/auth/requestbooking", owner, validBooking("r1", "form"))
+		decodeBody(t, w, &result)
+		if w.Code != code || result.State != state || result.StatusGazCrm != "Error" {
+			t.Errorf("required %v: code %d, result %+v, want %d %s", required, w.Code, result, code, state)
+		}
+		if got := sagaState(t, ts, "r1"); got != state {
+			t.Errorf("required %v: state %s, want %s", required, got, state)
+		}
+	}
+}
+
+func TestRecoverSagasStops(t *testing.T) {
+
+	ts := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ts.recoverSagas(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("recovery is running after shutdown")
+	}
+}
@@ -1,15 +1,24 @@
 package apiserver
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/attachment"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/gazcrm"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/invoice"
 	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/mailing"
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/payment"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/ratelimit"
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/token"
 )
 
 //errors
@@ -19,6 +28,9 @@ var (
 	errJwt                      = errors.New("token error")
 	errFindUser                 = errors.New("user not found")
 	errMssql                    = errors.New("mssql error")
+	errBookingNotFound          = errors.New("booking not found")
+	errBookingForbidden         = errors.New("booking is available to originating client or admin only")
+	errPostgres                 = errors.New("postgres error")
 )
 
 //responses
@@ -31,23 +43,101 @@ var (
 	errPg              = "error postgres storing"
 )
 
+//request context keys
+type ctxKey int
+
+const (
+	ctxKeyUserId ctxKey = iota
+	ctxKeyUserRole
+	ctxKeyAudit
+)
+
 //server configure
 type server struct {
-	router *mux.Router
-	store  store.Store
-	config *model.Service
-	client *http.Client
+	router      *mux.Router
+	store       store.Store
+	config      *model.Service
+	client      *http.Client
+	gazcrm      *gazcrm.Client
+	gazcrmTest  *gazcrm.Client
+	mailing     *mailing.Queue
+	payment     payment.Provider
+	bill        *invoice.Generator
+	attachments *attachment.Service
+	tokens      token.Service
+	users       *userAdmin
+	authLimits  *authLimits
+	rateLimits  *ratelimit.Buckets
+	catalog     *catalogCache
+	nonces      *nonceCache
+	proxies     []*net.IPNet
 }
 
-func newServer(store store.Store, config *model.Service, client *http.Client) *server {
+func newServer(store store.Store, config *model.Service, client *http.Client) (*server, error) {
+
+	provider, err := payment.New(config)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		logger.WarningLogger.Println("payment provider is not configured, payment routes disabled")
+	}
+	if config.Spec.Client.UrlGazCrmEvents == "" {
+		logger.WarningLogger.Println("gazcrm events url is not configured, paid and cancelled bookings are not sent to gazcrm")
+	}
+
+	var bill *invoice.Generator
+	if config.Spec.Bill.Font != "" {
+		bill, err = invoice.New(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attachments, err := attachment.New(config)
+	if err != nil {
+		return nil, err
+	}
+	if attachments == nil {
+		logger.WarningLogger.Println("attachments dir is not configured, attachment routes disabled")
+	}
+
+	crm, err := gazcrm.New(client, false, config)
+	if err != nil {
+		return nil, err
+	}
+	crmTest, err := gazcrm.New(client, true, config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies, err := parseProxies(config.Spec.Proxies.Trusted)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &server{
-		router: mux.NewRouter(),
-		store:  store,
-		config: config,
-		client: client,
+		router:      mux.NewRouter(),
+		store:       store,
+		config:      config,
+		client:      client,
+		gazcrm:      crm,
+		gazcrmTest:  crmTest,
+		mailing:     mailing.New(config, store.Data()),
+		payment:     provider,
+		bill:        bill,
+		attachments: attachments,
+		tokens:      token.New(config),
+		users:       &userAdmin{store: store},
+		authLimits:  newAuthLimits(config, store.User()),
+		rateLimits:  newRateLimits(config),
+		catalog:     newCatalogCache(),
+		nonces:      newNonceCache(),
+		proxies:     proxies,
 	}
+	s.authLimits.restore(time.Now())
 	s.configureRouter()
-	return s
+	return s, nil
 }
 
 //write new token struct
@@ -76,6 +166,22 @@ func newResponseBooking(statusms string, responsems string, statusgcrm string, r
 	}
 }
 
+//gaz crm client by testmod
+func (s *server) gazCrm(test bool) *gazcrm.Client {
+	if test {
+		return s.gazcrmTest
+	}
+	return s.gazcrm
+}
+
+//gaz crm message for response, crm message if crm responded
+func gazCrmMessage(resp *model.ResponseGazCrm, err error) string {
+	if resp != nil && resp.Message != "" {
+		return resp.Message
+	}
+	return err.Error()
+}
+
 //write http error
 func (s *server) error(w http.ResponseWriter, r *http.Request, code int, err error) {
 	s.respond(w, r, code, map[string]string{"error": err.Error()})
@@ -91,15 +197,23 @@ func (s *server) respond(w http.ResponseWriter, r *http.Request, code int, data
 }
 
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.realIP(r)
+	if s.cors(w, r) {
+		return
+	}
 	s.router.ServeHTTP(w, r)
 }
 
 func (s *server) configureRouter() {
+	s.router.Use(s.middleWareBody, s.middleWareGzip)
 	//open
-	s.router.HandleFunc("/authentication", s.handleAuth()).Methods("POST")
+	s.router.Handle("/authentication", s.middleWareAudit(s.handleAuth())).Methods("POST")
+	if s.payment != nil {
+		s.router.HandleFunc("/payment/callback", s.handlePaymentCallback()).Methods("POST")
+	}
 	//private
 	auth := s.router.PathPrefix("/auth").Subrouter()
-	auth.Use(s.middleWare)
+	auth.Use(s.middleWareAudit, s.middleWare, s.middleWareRateLimit)
 	//booking, forms submit
 	auth.HandleFunc("/requestbooking", s.handleRequestBooking()).Methods("POST")
 	auth.HandleFunc("/requestform", s.handleRequestForm()).Methods("POST")
@@ -107,6 +221,21 @@ func (s *server) configureRouter() {
 	auth.HandleFunc("/requestleadget", s.handleRequestLeadGetGazCrm()).Methods("POST")
 	auth.HandleFunc("/requestworklist", s.handleRequestWorkListGazCrm()).Methods("POST")
 	auth.HandleFunc("/requeststatus", s.handleRequestStatusGazCrm()).Methods("POST")
+	//booking status
+	auth.HandleFunc("/bookings/{request_id}", s.handleBookingStatus()).Methods("GET")
+	//payment
+	if s.payment != nil {
+		auth.HandleFunc("/bookings/{request_id}/payment", s.handleCreatePayment()).Methods("POST")
+	}
+	//bill
+	auth.HandleFunc("/bookings/{request_id}/bill", s.handleBill()).Methods("GET")
+	//cancel
+	auth.HandleFunc("/bookings/{request_id}/cancel", s.handleCancelBooking()).Methods("POST")
+	//attachments
+	if s.attachments != nil {
+		auth.HandleFunc("/attachments", s.handleUploadAttachment()).Methods("POST")
+		auth.HandleFunc("/attachments/{attachment_id}", s.handleAttachment()).Methods("GET")
+	}
 	//stock
 	auth.HandleFunc("/getdatastocks", s.handleGetDataStocks()).Methods("GET")
 	//prices
@@ -121,33 +250,70 @@ func (s *server) configureRouter() {
 	auth.HandleFunc("/getpacketsdata", s.handlePacketsData()).Methods("GET")
 	//colors
 	auth.HandleFunc("/getcolorsdata", s.handleColorsData()).Methods("GET")
+	//user administration
+	admin := auth.PathPrefix("/admin").Subrouter()
+	admin.Use(s.middleWareAdmin)
+	admin.HandleFunc("/users", s.handleListUsers()).Methods("GET")
+	admin.HandleFunc("/users", s.handleCreateUser()).Methods("POST")
+	admin.HandleFunc("/users/{user_id}", s.handleUser()).Methods("GET")
+	admin.HandleFunc("/users/{user_id}", s.handleDeleteUser()).Methods("DELETE")
+	admin.HandleFunc("/users/{user_id}/disable", s.handleDisableUser(true)).Methods("POST")
+	admin.HandleFunc("/users/{user_id}/enable", s.handleDisableUser(false)).Methods("POST")
+	admin.HandleFunc("/users/{user_id}/password", s.handleUserPassword()).Methods("POST")
+	admin.HandleFunc("/users/{user_id}/role", s.handleUserRole()).Methods("POST")
+	admin.HandleFunc("/audit", s.handleAudit()).Methods("GET")
+	admin.HandleFunc("/lockouts", s.handleLockouts()).Methods("GET")
+	admin.HandleFunc("/lockouts/unlock", s.handleUnlock()).Methods("POST")
+	admin.HandleFunc("/ratelimits", s.handleRateLimits()).Methods("GET")
+	//gaz crm signed webhooks
+	webhook := s.router.PathPrefix("/webhook").Subrouter()
+	webhook.Use(s.middleWareAudit, s.middleWareWebhook)
+	webhook.HandleFunc("/requestleadget", s.handleRequestLeadGetGazCrm()).Methods("POST")
+	webhook.HandleFunc("/requestworklist", s.handleRequestWorkListGazCrm()).Methods("POST")
+	webhook.HandleFunc("/requeststatus", s.handleRequestStatusGazCrm()).Methods("POST")
 }
 
 //handle Auth
 func (s *server) handleAuth() http.HandlerFunc {
 
-	var req model.User1
-
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			s.error(w, r, http.StatusBadRequest, errReg)
-			logger.ErrorLogger.Println(err)
+
+		req := model.User1{}
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+
+		//unknown email and wrong password get the same response
+		ip, email, now := clientIP(r), authLimitEmail(req.Email), time.Now()
+		if d := s.authLimits.reserve(ip, email, now); d > 0 {
+			s.authLocked(w, r, d)
+			logger.ErrorLogger.Println("auth locked: email=" + email + " ip=" + ip)
 			return
 		}
 
 		u, err := s.store.User().FindUser(req.Email, req.Password)
 		if err != nil {
+			s.authLimits.fail(ip, email, now)
 			s.error(w, r, http.StatusUnauthorized, errIncorrectEmailOrPassword)
 			logger.ErrorLogger.Println(err)
 			return
 		}
+		s.authLimits.success(ip, email)
 
-		token, datetime_exp, err := s.store.User().CreateToken(uint64(u.ID), s.config)
+		token, datetime_exp, err := s.tokens.Issue(uint64(u.ID))
 		if err != nil {
 			s.error(w, r, http.StatusBadRequest, errJwt)
 			logger.ErrorLogger.Println(err)
 			return
 		}
+
+		auditUser(r, uint64(u.ID), token)
+
+		if err := s.store.User().UpdateLastLogin(uint64(u.ID)); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+
 		token_data := newToken(token, datetime_exp)
 		s.respond(w, r, http.StatusOK, token_data)
 		logger.InfoLogger.Println("token issued success")
@@ -161,82 +327,101 @@ func (s *server) middleWare(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		//extract user_id
-		user_id, err := s.store.User().ExtractTokenMetadata(r, s.config)
+		tokenString := token.FromRequest(r)
+		claims, err := s.tokens.Verify(tokenString)
 		if err != nil {
 			s.error(w, r, http.StatusUnauthorized, errJwt)
 			logger.ErrorLogger.Println(err)
 			return
 		}
 
-		if err := s.store.User().FindUserid(user_id.UserId); err != nil {
+		auditUser(r, claims.UserId, tokenString)
+
+		role, err := s.store.User().FindUserid(claims.UserId)
+		if err != nil {
 			s.error(w, r, http.StatusUnauthorized, errFindUser)
 			logger.ErrorLogger.Println(err)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), ctxKeyUserId, claims.UserId)
+		ctx = context.WithValue(ctx, ctxKeyUserRole, role)
+		next.ServeHTTP(w, r.WithContext(ctx))
 
 	})
 
 }
 
+//user id of authorized request
+func userId(r *http.Request) uint64 {
+	id, _ := r.Context().Value(ctxKeyUserId).(uint64)
+	return id
+}
+
+//role of authorized request, loaded by auth middleware
+func userRole(r *http.Request) string {
+	role, _ := r.Context().Value(ctxKeyUserRole).(string)
+	return role
+}
+
+//booking is available to user of saga or admin
+//forms and bookings stored before sagas have no owner, admin only
+func (s *server) canAccessBooking(saga *model.BookingSaga, userId uint64) (bool, error) {
+	if saga != nil && saga.UserId == userId {
+		return true, nil
+	}
+	role, err := s.store.User().FindUserRole(userId)
+	if err != nil {
+		return false, err
+	}
+	return role == model.RoleAdmin, nil
+}
+
 //handle Client Data
 func (s *server) handleRequestBooking() http.HandlerFunc {
 
-	var errMs string
-
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		req := model.DataBooking{}
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+
+		req.Normalize()
+
+		if err := req.ValidateDataBooking(); err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			logger.ErrorLogger.Println(err)
 			return
 		}
 
-		resp, err := s.store.Data().QueryInsertMssql(req)
-		if err != nil {
-			s.error(w, r, http.StatusBadRequest, errMssql)
+		if err := s.checkAttachments(req.Attachments, userId(r)); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
 			logger.ErrorLogger.Println(err)
-			logger.ErrorLogger.Println(resp)
 			return
 		}
 
-		if resp != "Обработка данных прошла успешно" {
-			errMs = "Error"
-			logger.ErrorLogger.Println(resp)
-		} else {
-			errMs = "Ok"
-			logger.InfoLogger.Println("data booking stored in mssql")
-
-			//respm, err := s.store.Data().CallMSMailing(req, s.config)
-			//if err != nil {
-			//ErrorLogger.Println(err)
-			//ErrorLogger.Println(respm)
-			//}
-			//InfoLogger.Println("email=" + respm)
-
+		if err := s.startBookingSaga(req.RequestId, userId(r)); err == store.ErrRecordExists {
+			s.error(w, r, http.StatusConflict, errBookingExists)
+			logger.ErrorLogger.Println(err, req.RequestId)
+			return
+		} else if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		//request gazcrm api
-		respg, err := s.store.Data().RequestGazCrmApiBooking(req, s.config)
-		if err != nil {
+		code, result := s.runBookingSaga(r.Context(), req)
+		s.respond(w, r, code, result)
+
+		if err := s.store.Data().QueryInsertBookingResultPostgres(req.RequestId, *result); err != nil {
 			logger.ErrorLogger.Println(err)
 		}
-		if respg.Status != "OK" {
-			logger.ErrorLogger.Println(respg)
-			s.respond(w, r, http.StatusBadRequest, newResponseBooking(errMs, resp, "Error", respg.Message))
-		} else {
-			logger.InfoLogger.Println("gazcrm booking data transfer success")
-			s.respond(w, r, http.StatusOK, newResponseBooking(errMs, resp, "Ok", respBooking))
-		}
 
-		//insert data in postgres
-		if err := s.store.Data().QueryInsertBookingPostgres(req); err != nil {
-			logger.ErrorLogger.Println(err)
-		} else {
-			logger.InfoLogger.Println("sites booking data stored")
+		if result.State == model.SagaCompleted {
+			s.completeBooking(req)
 		}
 
 	}
@@ -250,22 +435,29 @@ func (s *server) handleRequestForm() http.HandlerFunc {
 
 		req := model.DataForms{}
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+
+		req.Normalize()
+
+		if err := req.ValidateDataForms(); err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			logger.ErrorLogger.Println(err)
 			return
 		}
 
 		//request gazcrm api
-		respg, err := s.store.Data().RequestGazCrmApiForms(req, s.config)
+		respg, err := s.gazCrm(req.TestMod).Form(r.Context(), req)
+		var result *model.ResponseBooking
 		if err != nil {
 			logger.ErrorLogger.Println(err)
-		}
-		if respg.Status != "OK" {
-			logger.ErrorLogger.Println(respg)
-			s.respond(w, r, http.StatusBadRequest, newResponse("Error", respg.Message))
+			result = newResponseBooking("", "", "Error", gazCrmMessage(respg, err))
+			s.respond(w, r, http.StatusBadRequest, newResponse("Error", result.ResponseGazCrm))
 		} else {
 			logger.InfoLogger.Println("gazcrm form data transfer success")
+			result = newResponseBooking("", "", "Ok", respForm)
 			s.respond(w, r, http.StatusOK, newResponse("Ok", respForm))
 		}
 
@@ -276,6 +468,15 @@ func (s *server) handleRequestForm() http.HandlerFunc {
 			logger.InfoLogger.Println("sites form data stored")
 		}
 
+		if err := s.store.Data().QueryInsertBookingResultPostgres(req.RequestId, *result); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+
+		s.applyLeadEvent(req.RequestId, model.LeadEvent{Source: model.LeadSourceSite, EventName: model.LeadCreated, TimeEvent: req.TimeRequest})
+		if result.StatusGazCrm == "Ok" {
+			s.applyLeadEvent(req.RequestId, model.LeadEvent{Source: model.LeadSourceSite, EventName: model.LeadDelivered, TimeEvent: req.TimeRequest})
+		}
+
 	}
 
 }
@@ -288,9 +489,8 @@ func (s *server) handleRequestLeadGetGazCrm() http.HandlerFunc {
 
 		req := model.DataLeadGet{}
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			s.error(w, r, http.StatusBadRequest, err)
-			logger.ErrorLogger.Println(err)
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
 			return
 		}
 
@@ -302,6 +502,11 @@ func (s *server) handleRequestLeadGetGazCrm() http.HandlerFunc {
 		} else {
 			logger.InfoLogger.Println("gazcrm lead_get inserted in postgres")
 			s.respond(w, r, http.StatusOK, newResponse("Ok", respGazCrmLeadGet))
+			s.applyLeadEvent(req.Data.RequestId.RequestId, model.LeadEvent{
+				Source:    model.LeadSourceLeadGet,
+				EventName: req.Data.EventName.EventName,
+				TimeEvent: req.Data.TimeRequest.TimeRequest,
+			})
 		}
 
 	}
@@ -315,9 +520,8 @@ func (s *server) handleRequestWorkListGazCrm() http.HandlerFunc {
 
 		req := model.DataWorkList{}
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			s.error(w, r, http.StatusBadRequest, err)
-			logger.ErrorLogger.Println(err)
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
 			return
 		}
 
@@ -329,6 +533,17 @@ func (s *server) handleRequestWorkListGazCrm() http.HandlerFunc {
 		} else {
 			logger.InfoLogger.Println("gazcrm work_list inserted in postgres")
 			s.respond(w, r, http.StatusOK, newResponse("Ok", respGazCrmWorkList))
+			//work list is correlated with request by gaz crm client id
+			requestId, err := s.store.Data().QueryRequestIdByGazCrmClientPostgres(req.Data.GazcrmClientId.GazcrmClientId)
+			if err != nil {
+				logger.WarningLogger.Println(err)
+				return
+			}
+			s.applyLeadEvent(requestId, model.LeadEvent{
+				Source:    model.LeadSourceWorkList,
+				EventName: req.Data.EventName.EventName,
+				TimeEvent: req.Data.TimeRequest.TimeRequest,
+			})
 		}
 
 	}
@@ -342,9 +557,8 @@ func (s *server) handleRequestStatusGazCrm() http.HandlerFunc {
 
 		req := model.DataStatuses{}
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			s.error(w, r, http.StatusBadRequest, err)
-			logger.ErrorLogger.Println(err)
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
 			return
 		}
 
@@ -356,25 +570,90 @@ func (s *server) handleRequestStatusGazCrm() http.HandlerFunc {
 		} else {
 			logger.InfoLogger.Println("gazcrm statuses inserted in postgres")
 			s.respond(w, r, http.StatusOK, newResponse("Ok", respGazCrmStatuses))
+			s.applyLeadEvent(req.Data.RequestId.RequestId, model.LeadEvent{
+				Source:    model.LeadSourceStatus,
+				EventName: req.Data.EventName.EventName,
+				TimeEvent: req.Data.TimeRequest.TimeRequest,
+			})
 		}
 
 	}
 
 }
 
-//handle request stocks
-func (s *server) handleGetDataStocks() http.HandlerFunc {
+//apply event to lead state, rejected transitions are logged
+func (s *server) applyLeadEvent(requestId string, event model.LeadEvent) {
+
+	if requestId == "" {
+		return
+	}
+
+	t, err := s.store.Data().QueryApplyLeadEventPostgres(requestId, event)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return
+	}
+	if !t.Accepted {
+		logger.WarningLogger.Printf("lead %s: %s -> %s rejected (%s), event %s at %s",
+			t.RequestId, t.FromState, t.ToState, t.Reason, t.EventName, t.TimeEvent)
+		return
+	}
+
+	logger.InfoLogger.Printf("lead %s: %s -> %s", t.RequestId, t.FromState, t.ToState)
+
+}
+
+//handle booking status
+func (s *server) handleBookingStatus() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryStocksMssql()
+		requestId := mux.Vars(r)["request_id"]
 
+		data, err := s.store.Data().QueryBookingStatusPostgres(requestId)
+		if err == store.ErrRecordNotFound {
+			s.error(w, r, http.StatusNotFound, errBookingNotFound)
+			logger.ErrorLogger.Println(err)
+			return
+		}
 		if err != nil {
-			s.error(w, r, http.StatusBadRequest, errMssql)
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
+		ok, err := s.canAccessBooking(data.Saga, userId(r))
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, errPostgres)
 			logger.ErrorLogger.Println(err)
+			return
+		}
+		if !ok {
+			s.error(w, r, http.StatusForbidden, errBookingForbidden)
+			logger.ErrorLogger.Println(errBookingForbidden, requestId, userId(r))
+			return
 		}
 
 		s.respond(w, r, http.StatusOK, data)
+		logger.InfoLogger.Println("booking status sent")
+
+	}
+
+}
+
+//handle request stocks
+func (s *server) handleGetDataStocks() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryStocksMssql()
+		}); err != nil {
+			s.error(w, r, http.StatusBadRequest, errMssql)
+			logger.ErrorLogger.Println(err)
+			return
+		}
+
 		logger.InfoLogger.Println("data stocks sent")
 
 	}
@@ -386,14 +665,14 @@ func (s *server) handleBasicModelsPrice() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryBasicModelsPriceMssql()
-
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryBasicModelsPriceMssql()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data price basic models sent")
 
 	}
@@ -405,14 +684,14 @@ func (s *server) handleOptionsPrice() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryOptionsPriceMssql()
-
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryOptionsPriceMssql()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data price options sent")
 
 	}
@@ -424,14 +703,14 @@ func (s *server) handleGeneralPrice() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryGeneralPriceMssql()
-
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryGeneralPriceMssql()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data price general sent")
 
 	}
@@ -443,14 +722,14 @@ func (s *server) handleSprav() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QuerySprav()
-
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QuerySprav()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data sprav sent")
 
 	}
@@ -462,14 +741,14 @@ func (s *server) handleOptionsData() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryOptionsData()
-
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryOptionsData()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data options sent")
 
 	}
@@ -481,14 +760,14 @@ func (s *server) handleOptionsDataSprav() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryOptionsDataSprav()
-
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryOptionsDataSprav()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data options sprav sent")
 
 	}
@@ -500,14 +779,14 @@ func (s *server) handlePacketsData() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryPacketsData()
-
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryPacketsData()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data packets sent")
 
 	}
@@ -519,13 +798,14 @@ func (s *server) handleColorsData() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		data, err := s.store.Data().QueryColorsData()
-		if err != nil {
+		if err := s.respondCatalog(w, r, func() (interface{}, error) {
+			return s.store.Data().QueryColorsData()
+		}); err != nil {
 			s.error(w, r, http.StatusBadRequest, errMssql)
 			logger.ErrorLogger.Println(err)
+			return
 		}
 
-		s.respond(w, r, http.StatusOK, data)
 		logger.InfoLogger.Println("data colors sent")
 
 	}
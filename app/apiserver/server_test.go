@@ -0,0 +1,115 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/mailing"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/teststore"
+)
+
+//server over teststore, gaz crm stand-in accepts everything, mails are kept in memory
+type testServer struct {
+	*server
+	store     *teststore.Store
+	mails     *mailing.MemorySender
+	crmCalls  int32
+	mu        sync.Mutex
+	crmEvents []string
+}
+
+func newTestServer(t *testing.T) *testServer {
+
+	ts := &testServer{mails: &mailing.MemorySender{}}
+	crm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			var event model.DataGazCrmEvent
+			json.NewDecoder(r.Body).Decode(&event)
+			ts.mu.Lock()
+			ts.crmEvents = append(ts.crmEvents, event.Data.RequestId.RequestId+" "+event.Data.EventName.EventName)
+			ts.mu.Unlock()
+		} else {
+			atomic.AddInt32(&ts.crmCalls, 1)
+		}
+		json.NewEncoder(w).Encode(model.ResponseGazCrm{Status: "OK", Message: "message"})
+	}))
+	t.Cleanup(crm.Close)
+
+	config := &model.Service{}
+	config.Spec.Jwt.TokenDecode = "test secret"
+	config.Spec.Jwt.LifeTerm = 1
+	config.Spec.Client.UrlGazCrm = crm.URL
+	config.Spec.Client.UrlGazCrmTest = crm.URL
+	config.Spec.Client.UrlGazCrmEvents = crm.URL + "/events"
+	config.Spec.Client.UrlGazCrmEventsTest = crm.URL + "/events"
+	config.Spec.Attachments.Dir = t.TempDir()
+	config.Spec.Mailing.Transport = "memory"
+	config.Spec.Mailing.Workers = 1
+	config.Spec.Payment.Provider = "fake"
+	config.Spec.Payment.Secret = "payment secret"
+	config.Spec.Payment.Dev = true
+
+	st := teststore.New(config)
+	s, err := newServer(st, config, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.server, ts.store = s, st
+	s.mailing.Close(context.Background())
+	s.mailing = mailing.NewWithSender(config, st.Data(), ts.mails)
+
+	return ts
+}
+
+//gaz crm lead requests
+func (ts *testServer) crm() int {
+	return int(atomic.LoadInt32(&ts.crmCalls))
+}
+
+//gaz crm lead events, "request_id event_name"
+func (ts *testServer) events() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return append([]string(nil), ts.crmEvents...)
+}
+
+//request of user through production handler, userId 0 - no token, body is json encoded
+func (ts *testServer) do(t *testing.T, method string, path string, userId uint64, body interface{}) *httptest.ResponseRecorder {
+
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, path, &b)
+	r.Header.Set("Content-Type", "application/json")
+	if userId != 0 {
+		token, _, err := ts.tokens.Issue(userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, r)
+	return w
+}
+
+//wait for queued mails
+func (ts *testServer) flush(t *testing.T) []mailing.Message {
+	if err := ts.mailing.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return ts.mails.Messages()
+}
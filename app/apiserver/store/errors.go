@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
+	ErrRecordExists   = errors.New("record already exists")
 )
@@ -1,10 +1,8 @@
 package store
 
 import (
-	"net/http"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
 )
 
@@ -12,12 +10,22 @@ import (
 type UserRepository interface {
 	//auth methods
 	FindUser(string, string) (*model.User1, error)
-	FindUserid(uint64) error
-	//jwt methods
-	CreateToken(uint64, *model.Service) (string, time.Time, error)
-	ExtractTokenMetadata(*http.Request, *model.Service) (*model.AccessDetails, error)
-	VerifyToken(*http.Request, *model.Service) (*jwt.Token, error)
-	ExtractToken(*http.Request) string
+	FindUserid(uint64) (string, error)
+	FindUserRole(uint64) (string, error)
+	UpdateLastLogin(uint64) error
+	//admin methods
+	CreateUser(model.User) (uint64, error)
+	ListUsers() ([]model.User, error)
+	FindUserById(uint64) (*model.User, error)
+	UpdateUserDisabled(uint64, bool) error
+	UpdateUserPassword(uint64, string) error
+	UpdateUserRole(uint64, string) error
+	DeleteUser(uint64) error
+	//auth lockouts
+	SaveAuthLockout(model.AuthLockout) error
+	FindAuthLockout(string, string) (*model.AuthLockout, error)
+	ListAuthLockouts(time.Time) ([]model.AuthLockout, error)
+	DeleteAuthLockout(string, string) (bool, error)
 }
 
 //data repository
@@ -26,12 +34,17 @@ type DataRepository interface {
 	//sites methods
 	QueryInsertBookingPostgres(model.DataBooking) error
 	QueryInsertFormsPostgres(model.DataForms) error
-	RequestGazCrmApiBooking(model.DataBooking, *model.Service) (*model.ResponseGazCrm, error)
-	RequestGazCrmApiForms(model.DataForms, *model.Service) (*model.ResponseGazCrm, error)
 	//gaz crm
 	QueryInsertLeadGetPostgres(model.DataLeadGet) error
 	QueryInsertWorkListsPostgres(model.DataWorkList) error
 	QueryInsertStatusesPostgres(model.DataStatuses) error
+	//booking status
+	QueryInsertBookingResultPostgres(string, model.ResponseBooking) error
+	QueryBookingStatusPostgres(string) (*model.BookingStatus, error)
+	//lead lifecycle
+	QueryApplyLeadEventPostgres(string, model.LeadEvent) (*model.LeadTransition, error)
+	QueryLeadStatePostgres(string) (*model.LeadState, error)
+	QueryRequestIdByGazCrmClientPostgres(string) (string, error)
 	//get methods
 	QueryStocksMssql() ([]model.DataStocks, error)
 	QueryBasicModelsPriceMssql() ([]model.DataBasicModelsPrice, error)
@@ -42,7 +55,31 @@ type DataRepository interface {
 	QueryOptionsDataSprav() ([]model.DataOptionsSprav, error)
 	QueryPacketsData() ([]model.DataPackets, error)
 	QueryColorsData() ([]model.DataColors, error)
-
-	//mailing call method
-	CallMSMailing(model.DataBooking, *model.Service) (string, error)
+	//payment
+	QueryInsertPaymentPostgres(model.Payment) error
+	QueryPaymentPostgres(string) (*model.Payment, error)
+	QueryBookingPaymentsPostgres(string) ([]model.Payment, error)
+	QueryUpdatePaymentStatusPostgres(string, string) (bool, error)
+	QueryPaidMssql(model.Payment) (string, error)
+	//bill
+	QueryInsertBillPostgres(string, string, []byte) error
+	QueryBillPostgres(string) (string, []byte, error)
+	//mailing
+	QueryInsertMailingStatusPostgres(model.MailingStatus) error
+	//booking saga
+	QueryInsertSagaPostgres(string, uint64) error
+	QueryUpdateSagaStatePostgres(string, string) error
+	QueryCompareSagaStatePostgres(string, string, string) (bool, error)
+	QueryInsertSagaStepPostgres(string, model.SagaStep) error
+	QuerySagaPostgres(string) (*model.BookingSaga, error)
+	QueryClaimStaleSagasPostgres(time.Duration) ([]string, error)
+	QueryCancelMssql(model.DataBooking, string) (string, error)
+	QueryInsertCancellationPostgres(model.Cancellation) error
+	//audit
+	QueryInsertAuditPostgres(model.AuditEvent) error
+	QueryAuditPostgres(model.AuditFilter) ([]model.AuditEvent, error)
+	//attachments
+	QueryInsertAttachmentPostgres(model.Attachment) error
+	QueryAttachmentPostgres(string) (*model.Attachment, error)
+	QueryInsertBookingAttachmentsPostgres(string, []string) error
 }
@@ -1,15 +1,14 @@
 package sqlstore
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
 
 	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
 )
@@ -66,329 +65,22 @@ func (r *DataRepository) QueryInsertMssql(data model.DataBooking) (string, error
 	return mssql_respond, nil
 }
 
-//request GAZ CRM booking
-func (r *DataRepository) RequestGazCrmApiBooking(data model.DataBooking, config *model.Service) (*model.ResponseGazCrm, error) {
-
-	var response *model.ResponseGazCrm
-
-	b := &model.DataGazCrm{
-		Data: model.DataGazCrmReq{
-			TimeRequest: struct {
-				TimeRequest string "json:\"event_datetime\""
-			}{
-				TimeRequest: data.TimeRequest,
-			},
-			RequestId: struct {
-				RequestId string "json:\"request_id\""
-			}{
-				RequestId: data.RequestId,
-			},
-			SubdivisionsId: struct {
-				SubdivisionsId string "json:\"subdivisions_id\""
-			}{
-				SubdivisionsId: data.SubdivisionsId,
-			},
-			SubdivisionsName: struct {
-				SubdivisionsName string "json:\"subdivisions_name\""
-			}{
-				SubdivisionsName: data.SubdivisionsName,
-			},
-			FormName: struct {
-				FormName string "json:\"form_name\""
-			}{
-				FormName: data.FormName,
-			},
-			FormId: struct {
-				FormId string "json:\"id_form\""
-			}{
-				FormId: data.FormId,
-			},
-			HostName: struct {
-				HostName string "json:\"host_name\""
-			}{
-				HostName: data.HostName,
-			},
-			Division: struct {
-				Division string "json:\"division\""
-			}{
-				Division: data.Division,
-			},
-			Area: struct {
-				Area string "json:\"area\""
-			}{
-				Area: data.Area,
-			},
-			BrandName: struct {
-				BrandName string "json:\"brand_name\""
-			}{
-				BrandName: data.BrandName,
-			},
-			CarModel: struct {
-				CarModel string "json:\"car_model\""
-			}{
-				CarModel: data.CarModel,
-			},
-			ClientID: struct {
-				ClientID string "json:\"ClientID\""
-			}{
-				ClientID: data.Clientid,
-			},
-			MetricsType: struct {
-				MetricsType string "json:\"metrics_type\""
-			}{
-				MetricsType: data.MetricsType,
-			},
-			СlientIP: struct {
-				СlientIP string "json:\"client_IP\""
-			}{
-				СlientIP: data.СlientIP,
-			},
-			TypeClient: struct {
-				TypeClient string "json:\"client_type\""
-			}{
-				TypeClient: data.TypeClient,
-			},
-			CompanyName: struct {
-				CompanyName string "json:\"client_company_name\""
-			}{
-				CompanyName: data.CompanyName,
-			},
-			СlientName: struct {
-				СlientName string "json:\"client_name\""
-			}{
-				СlientName: data.Name,
-			},
-			ClientEmail: struct {
-				ClientEmail string "json:\"client_email\""
-			}{
-				ClientEmail: data.Email,
-			},
-			ClientPhoneNumber: struct {
-				ClientPhoneNumber string "json:\"client_phone_number\""
-			}{
-				ClientPhoneNumber: data.PhoneNumber,
-			},
-			Commentary: struct {
-				Commentary string "json:\"commentary\""
-			}{
-				Commentary: data.Comment,
-			},
-			AgreementMailing: struct {
-				AgreementMailing string "json:\"agreement_mailing\""
-			}{
-				AgreementMailing: data.Consentmailing,
-			},
-		},
-	}
-
-	//d_spaces, err := json.MarshalIndent(dataset, "", "    ")
-	//if err != nil {
-	//return "", err
-	//}
-
-	bodyBytesReq, err := json.Marshal(b)
-	if err != nil {
-		logger.ErrorLogger.Println(err)
-		return nil, err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*5))
-	defer cancel()
-
-	req, err := http.NewRequest(http.MethodPost, config.Spec.Client.UrlGazCrmTest, bytes.NewBuffer(bodyBytesReq))
-	if err != nil {
-		logger.ErrorLogger.Println(err)
-		return nil, err
-	}
-
-	req = req.WithContext(ctx)
-	c := &http.Client{}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		logger.ErrorLogger.Println(err)
-	}
-
-	defer resp.Body.Close()
-
-	bodyBytesResp, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		logger.ErrorLogger.Println(err)
-		return nil, err
-	}
-
-	if err := json.Unmarshal(bodyBytesResp, &response); err != nil {
-		return nil, err
-	}
-
-	return response, nil
-
-}
-
-//request GAZ CRM forms
-func (r *DataRepository) RequestGazCrmApiForms(data model.DataForms, config *model.Service) (*model.ResponseGazCrm, error) {
-
-	var response *model.ResponseGazCrm
-
-	b := &model.DataGazCrm{
-		Data: model.DataGazCrmReq{
-			TimeRequest: struct {
-				TimeRequest string "json:\"event_datetime\""
-			}{
-				TimeRequest: data.TimeRequest,
-			},
-			RequestId: struct {
-				RequestId string "json:\"request_id\""
-			}{
-				RequestId: data.RequestId,
-			},
-			SubdivisionsId: struct {
-				SubdivisionsId string "json:\"subdivisions_id\""
-			}{
-				SubdivisionsId: data.SubdivisionsId,
-			},
-			SubdivisionsName: struct {
-				SubdivisionsName string "json:\"subdivisions_name\""
-			}{
-				SubdivisionsName: data.SubdivisionsName,
-			},
-			FormName: struct {
-				FormName string "json:\"form_name\""
-			}{
-				FormName: data.FormName,
-			},
-			FormId: struct {
-				FormId string "json:\"id_form\""
-			}{
-				FormId: data.FormId,
-			},
-			HostName: struct {
-				HostName string "json:\"host_name\""
-			}{
-				HostName: data.HostName,
-			},
-			Division: struct {
-				Division string "json:\"division\""
-			}{
-				Division: data.Division,
-			},
-			Area: struct {
-				Area string "json:\"area\""
-			}{
-				Area: data.Area,
-			},
-			BrandName: struct {
-				BrandName string "json:\"brand_name\""
-			}{
-				BrandName: data.BrandName,
-			},
-			CarModel: struct {
-				CarModel string "json:\"car_model\""
-			}{
-				CarModel: data.CarModel,
-			},
-			ClientID: struct {
-				ClientID string "json:\"ClientID\""
-			}{
-				ClientID: data.Clientid,
-			},
-			MetricsType: struct {
-				MetricsType string "json:\"metrics_type\""
-			}{
-				MetricsType: data.MetricsType,
-			},
-			СlientIP: struct {
-				СlientIP string "json:\"client_IP\""
-			}{
-				СlientIP: data.СlientIP,
-			},
-			TypeClient: struct {
-				TypeClient string "json:\"client_type\""
-			}{
-				TypeClient: data.TypeClient,
-			},
-			CompanyName: struct {
-				CompanyName string "json:\"client_company_name\""
-			}{
-				CompanyName: data.CompanyName,
-			},
-			СlientName: struct {
-				СlientName string "json:\"client_name\""
-			}{
-				СlientName: data.Name,
-			},
-			ClientEmail: struct {
-				ClientEmail string "json:\"client_email\""
-			}{
-				ClientEmail: data.Email,
-			},
-			ClientPhoneNumber: struct {
-				ClientPhoneNumber string "json:\"client_phone_number\""
-			}{
-				ClientPhoneNumber: data.PhoneNumber,
-			},
-			Commentary: struct {
-				Commentary string "json:\"commentary\""
-			}{
-				Commentary: data.Comment,
-			},
-			AgreementMailing: struct {
-				AgreementMailing string "json:\"agreement_mailing\""
-			}{
-				AgreementMailing: data.Consentmailing,
-			},
-		},
-	}
-
-	bodyBytesReq, err := json.Marshal(b)
-	if err != nil {
-		return nil, err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*5))
-	defer cancel()
-
-	req, err := http.NewRequest(http.MethodPost, config.Spec.Client.UrlGazCrmTest, bytes.NewBuffer(bodyBytesReq))
-	if err != nil {
-		logger.ErrorLogger.Println(err)
-		return nil, err
-	}
-
-	req = req.WithContext(ctx)
-	c := &http.Client{}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		logger.ErrorLogger.Println(err)
-	}
-
-	defer resp.Body.Close()
-
-	bodyBytesResp, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		logger.ErrorLogger.Println(err)
-		return nil, err
-	}
-
-	if err := json.Unmarshal(bodyBytesResp, &response); err != nil {
-		logger.ErrorLogger.Println(err)
-		return nil, err
-	}
-
-	return response, nil
-
-}
-
 //insert booking in postgres
 func (r *DataRepository) QueryInsertBookingPostgres(data model.DataBooking) error {
 
 	query := `
-	insert into booking
+	insert into booking (request_id, action_type, uniq_mod_code, modification, mod_family,
+		mod_body_type, mod_engine, mod_base, mod_tuning, vin, price, client_type, inn, kpp,
+		ogrn, reg_address_code, delivery_address_code, delivery_address, hid,
+		client_company_name, representative_name, representative_surname, surname,
+		client_name, patronymic, passport_ser, passport_number, snils, date_of_birth,
+		client_email, client_phone_number, commentary, agreement_mailing, event_datetime,
+		file, bill_number, url_mod, clientid_google, ymuid, testmod)
 	values($1, $2, $3, $4, $5, $6, $7, $8, $9,
 		$10, $11, $12, $13, $14, $15, $16, $17, $18,
 		$19, $20, $21, $22, $23, $24, $25, $26, $27,
 		$28, $29, $30, $31, $32, $33, $34, $35, $36,
-		$37, $38, $39)`
+		$37, $38, $39, $40)`
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancelFunc()
@@ -460,11 +152,16 @@ func (r *DataRepository) QueryInsertBookingPostgres(data model.DataBooking) erro
 func (r *DataRepository) QueryInsertFormsPostgres(data model.DataForms) error {
 
 	query := `
-	insert into forms
+	insert into forms (event_datetime, request_id, subdivisions_id, subdivisions_name,
+		form_name, id_form, host_name, division, area, brand_name, car_model, clientid,
+		metrics_type, client_ip, client_type, client_company_name, client_name, client_email,
+		client_phone_number, commentary, agreement_mailing, action_type, modification,
+		mod_family, mod_body_type, mod_engine, mod_base, mod_tuning, vin, price, url_mod,
+		testmod)
 	values($1, $2, $3, $4, $5, $6, $7, $8, $9,
 		$10, $11, $12, $13, $14, $15, $16, $17, $18,
 		$19, $20, $21, $22, $23, $24, $25, $26, $27,
-		$28, $29, $30, $31)`
+		$28, $29, $30, $31, $32)`
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancelFunc()
@@ -507,6 +204,7 @@ func (r *DataRepository) QueryInsertFormsPostgres(data model.DataForms) error {
 		data.Vin,
 		data.PriceWithNds,
 		data.UrlMod,
+		data.TestMod,
 	)
 	if err != nil {
 		logger.ErrorLogger.Println(err)
@@ -527,7 +225,9 @@ func (r *DataRepository) QueryInsertFormsPostgres(data model.DataForms) error {
 func (r *DataRepository) QueryInsertLeadGetPostgres(data model.DataLeadGet) error {
 
 	query := `
-	insert into gazcrm_lead_get
+	insert into gazcrm_lead_get (event_datetime, event_name, request_id, subdivisions_id,
+		subdivisions_name, form_name, host_name, division, area, brand_name, clientid,
+		metrics_type)
 	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
@@ -540,18 +240,18 @@ func (r *DataRepository) QueryInsertLeadGetPostgres(data model.DataLeadGet) erro
 	}
 
 	_, err = tx.Exec(ctx, query,
-		data.Data.TimeRequest,
-		data.Data.EventName,
-		data.Data.RequestId,
-		data.Data.SubdivisionsId,
-		data.Data.SubdivisionsName,
-		data.Data.FormName,
-		data.Data.HostName,
-		data.Data.Division,
-		data.Data.Area,
-		data.Data.BrandName,
-		data.Data.ClientID,
-		data.Data.MetricsType,
+		data.Data.TimeRequest.TimeRequest,
+		data.Data.EventName.EventName,
+		data.Data.RequestId.RequestId,
+		data.Data.SubdivisionsId.SubdivisionsId,
+		data.Data.SubdivisionsName.SubdivisionsName,
+		data.Data.FormName.FormName,
+		data.Data.HostName.HostName,
+		data.Data.Division.Division,
+		data.Data.Area.Area,
+		data.Data.BrandName.BrandName,
+		data.Data.ClientID.ClientID,
+		data.Data.MetricsType.MetricsType,
 	)
 	if err != nil {
 		logger.ErrorLogger.Println(err)
@@ -571,7 +271,7 @@ func (r *DataRepository) QueryInsertLeadGetPostgres(data model.DataLeadGet) erro
 func (r *DataRepository) QueryInsertWorkListsPostgres(data model.DataWorkList) error {
 
 	query := `
-	insert into gazcrm_work_list
+	insert into gazcrm_work_list (event_datetime, event_name, gazcrm_client_id, gazcrm_worklist_id)
 	values($1, $2, $3, $4)`
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
@@ -584,10 +284,10 @@ func (r *DataRepository) QueryInsertWorkListsPostgres(data model.DataWorkList) e
 	}
 
 	_, err = tx.Exec(ctx, query,
-		data.Data.TimeRequest,
-		data.Data.EventName,
-		data.Data.GazcrmClientId,
-		data.Data.GazCrmWorkListId,
+		data.Data.TimeRequest.TimeRequest,
+		data.Data.EventName.EventName,
+		data.Data.GazcrmClientId.GazcrmClientId,
+		data.Data.GazCrmWorkListId.GazCrmWorkListId,
 	)
 	if err != nil {
 		logger.ErrorLogger.Println(err)
@@ -607,7 +307,8 @@ func (r *DataRepository) QueryInsertWorkListsPostgres(data model.DataWorkList) e
 func (r *DataRepository) QueryInsertStatusesPostgres(data model.DataStatuses) error {
 
 	query := `
-	insert into gazcrm_statuses
+	insert into gazcrm_statuses (event_datetime, event_name, request_id, gazcrm_client_id,
+		gazcrm_worklist_id, clientid, metrics_type)
 	values($1, $2, $3, $4, $5, $6, $7)`
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
@@ -620,13 +321,13 @@ func (r *DataRepository) QueryInsertStatusesPostgres(data model.DataStatuses) er
 	}
 
 	_, err = tx.Exec(ctx, query,
-		data.Data.TimeRequest,
-		data.Data.EventName,
-		data.Data.RequestId,
-		data.Data.GazcrmClientId,
-		data.Data.GazCrmWorkListId,
-		data.Data.ClientID,
-		data.Data.MetricsType,
+		data.Data.TimeRequest.TimeRequest,
+		data.Data.EventName.EventName,
+		data.Data.RequestId.RequestId,
+		data.Data.GazcrmClientId.GazcrmClientId,
+		data.Data.GazCrmWorkListId.GazCrmWorkListId,
+		data.Data.ClientID.ClientID,
+		data.Data.MetricsType.MetricsType,
 	)
 	if err != nil {
 		logger.ErrorLogger.Println(err)
@@ -1033,28 +734,992 @@ func (r *DataRepository) QueryColorsData() ([]model.DataColors, error) {
 
 }
 
-//call microservice mailing
-func (r *DataRepository) CallMSMailing(data model.DataBooking, config *model.Service) (string, error) {
+//insert booking result in postgres
+func (r *DataRepository) QueryInsertBookingResultPostgres(requestId string, data model.ResponseBooking) error {
+
+	query := `
+	insert into booking_results (request_id, status_ms, response_ms, status_gcrm, response_gcrm)
+	values($1, $2, $3, $4, $5)`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
 
-	bodyBytesReq, err := json.Marshal(data)
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		requestId,
+		data.StatusMs,
+		data.ResponseMs,
+		data.StatusGazCrm,
+		data.ResponseGazCrm,
+	)
 	if err != nil {
 		logger.ErrorLogger.Println(err)
-		return "", err
+		return err
+	}
+
+	return nil
+
+}
+
+//query booking status in postgres
+//booking or form, last result and gaz crm events ordered by event_datetime
+func (r *DataRepository) QueryBookingStatusPostgres(requestId string) (*model.BookingStatus, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	status := &model.BookingStatus{
+		RequestId: requestId,
+		Timeline:  []model.BookingEvent{},
+	}
+
+	booking := &model.DataBooking{}
+	err := r.store.dbPostgres.QueryRow(ctx, `
+	select request_id, action_type, uniq_mod_code, modification, mod_family, mod_body_type,
+		mod_engine, mod_base, mod_tuning, vin, price, client_type, inn, kpp, ogrn,
+		reg_address_code, delivery_address_code, delivery_address, hid, client_company_name,
+		representative_name, representative_surname, surname, client_name, patronymic,
+		passport_ser, passport_number, snils, date_of_birth, client_email, client_phone_number,
+		commentary, agreement_mailing, event_datetime, file, bill_number, url_mod,
+		clientid_google, ymuid, testmod
+	from booking where request_id = $1 limit 1`,
+		requestId).Scan(
+		&booking.RequestId,
+		&booking.ActionType,
+		&booking.UniqModCode,
+		&booking.Modification,
+		&booking.ModFamily,
+		&booking.ModBodyType,
+		&booking.ModEngine,
+		&booking.ModBase,
+		&booking.ModTuning,
+		&booking.Vin,
+		&booking.PriceWithNds,
+		&booking.TypeClient,
+		&booking.Inn,
+		&booking.Kpp,
+		&booking.Ogrn,
+		&booking.YurAddressCode,
+		&booking.DeliveryAddressCode,
+		&booking.DeliveryAddress,
+		&booking.Hid,
+		&booking.CompanyName,
+		&booking.RepresentativeName,
+		&booking.RepresentativeSurname,
+		&booking.Surname,
+		&booking.Name,
+		&booking.Patronymic,
+		&booking.PassportSer,
+		&booking.PassportNumber,
+		&booking.Snils,
+		&booking.DateOfBirth,
+		&booking.Email,
+		&booking.PhoneNumber,
+		&booking.Comment,
+		&booking.Consentmailing,
+		&booking.TimeRequest,
+		&booking.File,
+		&booking.BillNumber,
+		&booking.UrlMod,
+		&booking.Clientid,
+		&booking.Ymuid,
+		&booking.TestMod,
+	)
+	switch err {
+	case nil:
+		status.Booking = booking
+	case pgx.ErrNoRows:
+	default:
+		logger.ErrorLogger.Println(err)
+		return nil, err
 	}
 
-	resp, err := http.Post(config.Spec.Client.UrlMailingService, "application/json", bytes.NewBuffer(bodyBytesReq))
+	if status.Booking == nil {
+		form := &model.DataForms{}
+		err := r.store.dbPostgres.QueryRow(ctx, `
+		select event_datetime, request_id, subdivisions_id, subdivisions_name, form_name, id_form,
+			host_name, division, area, brand_name, car_model, clientid, metrics_type, client_ip,
+			client_type, client_company_name, client_name, client_email, client_phone_number,
+			commentary, agreement_mailing, action_type, modification, mod_family, mod_body_type,
+			mod_engine, mod_base, mod_tuning, vin, price, url_mod, testmod
+		from forms where request_id = $1 limit 1`,
+			requestId).Scan(
+			&form.TimeRequest,
+			&form.RequestId,
+			&form.SubdivisionsId,
+			&form.SubdivisionsName,
+			&form.FormName,
+			&form.FormId,
+			&form.HostName,
+			&form.Division,
+			&form.Area,
+			&form.BrandName,
+			&form.CarModel,
+			&form.Clientid,
+			&form.MetricsType,
+			&form.СlientIP,
+			&form.TypeClient,
+			&form.CompanyName,
+			&form.Name,
+			&form.Email,
+			&form.PhoneNumber,
+			&form.Comment,
+			&form.Consentmailing,
+			&form.ActionType,
+			&form.Modification,
+			&form.ModFamily,
+			&form.ModBodyType,
+			&form.ModEngine,
+			&form.ModBase,
+			&form.ModTuning,
+			&form.Vin,
+			&form.PriceWithNds,
+			&form.UrlMod,
+			&form.TestMod,
+		)
+		switch err {
+		case nil:
+			status.Form = form
+		case pgx.ErrNoRows:
+			return nil, store.ErrRecordNotFound
+		default:
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+	}
+
+	result := &model.BookingResult{}
+	err = r.store.dbPostgres.QueryRow(ctx, `
+	select status_ms, response_ms, status_gcrm, response_gcrm, to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SS')
+	from booking_results where request_id = $1
+	order by created_at desc limit 1`,
+		requestId).Scan(
+		&result.StatusMs,
+		&result.ResponseMs,
+		&result.StatusGazCrm,
+		&result.ResponseGazCrm,
+		&result.TimeResult,
+	)
+	switch err {
+	case nil:
+		status.Result = result
+	case pgx.ErrNoRows:
+	default:
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	//work list events carry gazcrm_client_id only, correlated via statuses
+	rows, err := r.store.dbPostgres.Query(ctx, `
+	select event_datetime, 'lead_get', event_name, '', ''
+	from gazcrm_lead_get where request_id = $1
+	union all
+	select event_datetime, 'status', event_name, gazcrm_client_id, gazcrm_worklist_id
+	from gazcrm_statuses where request_id = $1
+	union all
+	select event_datetime, 'work_list', event_name, gazcrm_client_id, gazcrm_worklist_id
+	from gazcrm_work_list where gazcrm_client_id in (
+		select gazcrm_client_id from gazcrm_statuses
+		where request_id = $1 and gazcrm_client_id <> '')
+	order by 1`,
+		requestId)
 	if err != nil {
 		logger.ErrorLogger.Println(err)
-		return "", err
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		event := model.BookingEvent{}
+
+		if err := rows.Scan(
+			&event.TimeEvent,
+			&event.Source,
+			&event.EventName,
+			&event.GazcrmClientId,
+			&event.GazCrmWorkListId,
+		); err != nil {
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+		status.Timeline = append(status.Timeline, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	state, err := r.QueryLeadStatePostgres(requestId)
+	switch err {
+	case nil:
+		status.LeadState = state
+	case store.ErrRecordNotFound:
+	default:
+		return nil, err
+	}
+
+	saga, err := r.QuerySagaPostgres(requestId)
+	switch err {
+	case nil:
+		status.Saga = saga
+	case store.ErrRecordNotFound:
+	default:
+		return nil, err
 	}
 
-	defer resp.Body.Close()
+	return status, nil
+
+}
+
+//apply gaz crm event to lead state in postgres
+//transition is recorded whether accepted or not, state is updated on accepted only
+func (r *DataRepository) QueryApplyLeadEventPostgres(requestId string, event model.LeadEvent) (*model.LeadTransition, error) {
 
-	bodyBytesResp, err := ioutil.ReadAll(resp.Body)
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	tx, err := r.store.dbPostgres.Begin(ctx)
 	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	var current *model.LeadState
+	state := &model.LeadState{}
+	err = tx.QueryRow(ctx,
+		"SELECT request_id, state, event_datetime FROM lead_states WHERE request_id = $1 FOR UPDATE",
+		requestId).Scan(&state.RequestId, &state.State, &state.TimeEvent)
+	switch err {
+	case nil:
+		current = state
+	case pgx.ErrNoRows:
+	default:
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	t := model.ApplyLeadEvent(requestId, current, event, r.store.config.Spec.Lead.Events)
+
+	if _, err := tx.Exec(ctx, `
+	insert into lead_transitions (request_id, from_state, to_state, event_name, event_datetime, accepted, reason)
+	values($1, $2, $3, $4, $5, $6, $7)`,
+		t.RequestId,
+		t.FromState,
+		t.ToState,
+		t.EventName,
+		t.TimeEvent,
+		t.Accepted,
+		t.Reason,
+	); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	if t.Accepted {
+		if _, err := tx.Exec(ctx, `
+		insert into lead_states (request_id, state, event_datetime)
+		values($1, $2, $3)
+		on conflict (request_id) do update
+		set state = excluded.state, event_datetime = excluded.event_datetime, updated_at = now()`,
+			t.RequestId,
+			t.ToState,
+			t.TimeEvent,
+		); err != nil {
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return t, nil
+
+}
+
+//query current lead state in postgres
+func (r *DataRepository) QueryLeadStatePostgres(requestId string) (*model.LeadState, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	state := &model.LeadState{}
+	if err := r.store.dbPostgres.QueryRow(ctx,
+		"SELECT request_id, state, event_datetime FROM lead_states WHERE request_id = $1",
+		requestId).Scan(&state.RequestId, &state.State, &state.TimeEvent); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return state, nil
+
+}
+
+//query request_id by gaz crm client id (work list events)
+func (r *DataRepository) QueryRequestIdByGazCrmClientPostgres(gazcrmClientId string) (string, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	var requestId string
+	if err := r.store.dbPostgres.QueryRow(ctx, `
+	select request_id from gazcrm_statuses
+	where gazcrm_client_id = $1 and request_id <> ''
+	order by event_datetime desc limit 1`,
+		gazcrmClientId).Scan(&requestId); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
 		return "", err
 	}
 
-	return string(bodyBytesResp), nil
+	return requestId, nil
+
+}
+
+//insert mailing status in postgres
+func (r *DataRepository) QueryInsertMailingStatusPostgres(data model.MailingStatus) error {
+
+	query := `
+	insert into mailing_statuses (request_id, client_email, action_type, brand_name, status, attempts, error)
+	values($1, $2, $3, $4, $5, $6, $7)`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		data.RequestId,
+		data.Email,
+		data.ActionType,
+		data.BrandName,
+		data.Status,
+		data.Attempts,
+		data.Error,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//insert payment in postgres
+func (r *DataRepository) QueryInsertPaymentPostgres(data model.Payment) error {
+
+	query := `
+	insert into payments (payment_id, request_id, amount, currency, status, provider, provider_payment_id, payment_url)
+	values($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		data.PaymentId,
+		data.RequestId,
+		data.Amount,
+		data.Currency,
+		data.Status,
+		data.Provider,
+		data.ProviderPaymentId,
+		data.PaymentUrl,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//query payment in postgres
+func (r *DataRepository) QueryPaymentPostgres(paymentId string) (*model.Payment, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	p := &model.Payment{}
+	if err := r.store.dbPostgres.QueryRow(ctx, `
+	select payment_id, request_id, amount, currency, status, provider, provider_payment_id, payment_url
+	from payments where payment_id = $1`,
+		paymentId).Scan(
+		&p.PaymentId,
+		&p.RequestId,
+		&p.Amount,
+		&p.Currency,
+		&p.Status,
+		&p.Provider,
+		&p.ProviderPaymentId,
+		&p.PaymentUrl,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return p, nil
+
+}
+
+//select payments of booking from postgres
+func (r *DataRepository) QueryBookingPaymentsPostgres(requestId string) ([]model.Payment, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	rows, err := r.store.dbPostgres.Query(ctx, `
+	select payment_id, request_id, amount, currency, status, provider, provider_payment_id, payment_url
+	from payments where request_id = $1
+	order by created_at`,
+		requestId)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	payments := []model.Payment{}
+	for rows.Next() {
+		p := model.Payment{}
+		if err := rows.Scan(
+			&p.PaymentId,
+			&p.RequestId,
+			&p.Amount,
+			&p.Currency,
+			&p.Status,
+			&p.Provider,
+			&p.ProviderPaymentId,
+			&p.PaymentUrl,
+		); err != nil {
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return payments, nil
+
+}
+
+//update pending payment status in postgres
+//false if payment is not pending anymore (repeated callback)
+func (r *DataRepository) QueryUpdatePaymentStatusPostgres(paymentId string, status string) (bool, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	tag, err := r.store.dbPostgres.Exec(ctx, `
+	update payments set status = $2, updated_at = now()
+	where payment_id = $1 and status = $3`,
+		paymentId,
+		status,
+		model.PaymentPending,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+
+}
+
+//query booking paid mssql
+func (r *DataRepository) QueryPaidMssql(data model.Payment) (string, error) {
+
+	var mssql_respond string
+
+	_, err := r.store.dbMssql.Exec(r.store.config.Spec.Queryies.BookingPaid,
+		sql.Named("ИдентификаторОбращения", data.RequestId),
+		sql.Named("ИдентификаторПлатежа", data.PaymentId),
+		sql.Named("СуммаОплаты", float64(data.Amount)/100),
+		sql.Named("Ошибка", sql.Out{Dest: &mssql_respond}),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return mssql_respond, nil
+}
+
+//insert bill pdf in postgres
+func (r *DataRepository) QueryInsertBillPostgres(requestId string, billNumber string, pdf []byte) error {
+
+	query := `
+	insert into bills (request_id, bill_number, pdf)
+	values($1, $2, $3)
+	on conflict (request_id) do nothing`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		requestId,
+		billNumber,
+		pdf,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//query bill pdf in postgres
+func (r *DataRepository) QueryBillPostgres(requestId string) (string, []byte, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	var billNumber string
+	var pdf []byte
+	if err := r.store.dbPostgres.QueryRow(ctx,
+		"SELECT bill_number, pdf FROM bills WHERE request_id = $1",
+		requestId).Scan(&billNumber, &pdf); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil, store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
+		return "", nil, err
+	}
+
+	return billNumber, pdf, nil
+
+}
+
+//insert attachment in postgres
+func (r *DataRepository) QueryInsertAttachmentPostgres(data model.Attachment) error {
+
+	query := `
+	insert into attachments (attachment_id, user_id, file_name, content_type, size, sha256, created_at)
+	values($1, $2, $3, $4, $5, $6, now())`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		data.Id,
+		data.UserId,
+		data.FileName,
+		data.ContentType,
+		data.Size,
+		data.Sha256,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//query attachment in postgres
+func (r *DataRepository) QueryAttachmentPostgres(id string) (*model.Attachment, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	a := &model.Attachment{}
+	if err := r.store.dbPostgres.QueryRow(ctx,
+		"SELECT attachment_id, user_id, file_name, content_type, size, sha256 FROM attachments WHERE attachment_id = $1",
+		id).Scan(&a.Id, &a.UserId, &a.FileName, &a.ContentType, &a.Size, &a.Sha256); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return a, nil
+
+}
+
+//link attachments to booking
+func (r *DataRepository) QueryInsertBookingAttachmentsPostgres(requestId string, ids []string) error {
+
+	query := `
+	insert into booking_attachments (request_id, attachment_id)
+	values($1, $2)
+	on conflict do nothing`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	tx, err := r.store.dbPostgres.Begin(ctx)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	defer tx.Rollback(context.Background())
+
+	for _, id := range ids {
+		if _, err := tx.Exec(ctx, query, requestId, id); err != nil {
+			logger.ErrorLogger.Println(err)
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+
+}
+
+//insert booking saga in postgres, ErrRecordExists if request id is known
+func (r *DataRepository) QueryInsertSagaPostgres(requestId string, userId uint64) error {
+
+	query := `
+	insert into booking_sagas (request_id, state, user_id, created_at, updated_at)
+	values($1, $2, $3, now(), now())
+	on conflict (request_id) do nothing`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	tag, err := r.store.dbPostgres.Exec(ctx, query, requestId, model.SagaRunning, userId)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrRecordExists
+	}
+
+	return nil
+
+}
+
+//update booking saga state in postgres
+func (r *DataRepository) QueryUpdateSagaStatePostgres(requestId string, state string) error {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx,
+		"UPDATE booking_sagas SET state = $2, updated_at = now() WHERE request_id = $1",
+		requestId, state)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//update booking saga state if current state is from, false if state differs
+func (r *DataRepository) QueryCompareSagaStatePostgres(requestId string, from string, to string) (bool, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	tag, err := r.store.dbPostgres.Exec(ctx,
+		"UPDATE booking_sagas SET state = $3, updated_at = now() WHERE request_id = $1 AND state = $2",
+		requestId, from, to)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+
+}
+
+//insert booking saga step in postgres
+func (r *DataRepository) QueryInsertSagaStepPostgres(requestId string, step model.SagaStep) error {
+
+	query := `
+	insert into booking_saga_steps (request_id, step, status, error, created_at)
+	values($1, $2, $3, $4, now())`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		requestId,
+		step.Step,
+		step.Status,
+		step.Error,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//query booking saga with steps in postgres
+func (r *DataRepository) QuerySagaPostgres(requestId string) (*model.BookingSaga, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	saga := &model.BookingSaga{
+		RequestId: requestId,
+		Steps:     []model.SagaStep{},
+	}
+	if err := r.store.dbPostgres.QueryRow(ctx,
+		"SELECT state, user_id FROM booking_sagas WHERE request_id = $1",
+		requestId).Scan(&saga.State, &saga.UserId); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	rows, err := r.store.dbPostgres.Query(ctx, `
+	select step, status, error, to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SS')
+	from booking_saga_steps where request_id = $1
+	order by created_at`,
+		requestId)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		step := model.SagaStep{}
+		if err := rows.Scan(&step.Step, &step.Status, &step.Error, &step.TimeEvent); err != nil {
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+		saga.Steps = append(saga.Steps, step)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return saga, nil
+
+}
+
+//claim sagas running longer than age for recovery, rows locked by other instances are skipped
+//claimed sagas are recovering, claims of a crashed instance expire after age
+func (r *DataRepository) QueryClaimStaleSagasPostgres(age time.Duration) ([]string, error) {
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	tx, err := r.store.dbPostgres.Begin(ctx)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+	SELECT request_id FROM booking_sagas
+	WHERE state IN ($1, $2) AND updated_at < now() - $3::interval
+	ORDER BY updated_at
+	FOR UPDATE SKIP LOCKED`,
+		model.SagaRunning, model.SagaRecovering, fmt.Sprintf("%d seconds", int(age.Seconds())))
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE booking_sagas SET state = $2, updated_at = now() WHERE request_id = ANY($1)",
+		ids, model.SagaRecovering); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return ids, nil
+
+}
+
+//cancel booking reservation in mssql
+func (r *DataRepository) QueryCancelMssql(data model.DataBooking, reason string) (string, error) {
+
+	var mssql_respond string
+
+	_, err := r.store.dbMssql.Exec(r.store.config.Spec.Queryies.BookingCancel,
+		sql.Named("ИдентификаторОбращения", data.RequestId),
+		sql.Named("ПричинаОтмены", reason),
+		sql.Named("Ошибка", sql.Out{Dest: &mssql_respond}),
+		sql.Named("ВыполнитьТестовыйВызов", data.TestMod),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return mssql_respond, nil
+}
+
+//insert booking cancellation in postgres
+func (r *DataRepository) QueryInsertCancellationPostgres(data model.Cancellation) error {
+
+	query := `
+	insert into booking_cancellations (request_id, user_id, reason, created_at)
+	values($1, $2, $3, now())`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		data.RequestId,
+		data.UserId,
+		data.Reason,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//insert audit event in postgres
+func (r *DataRepository) QueryInsertAuditPostgres(data model.AuditEvent) error {
+
+	query := `
+	insert into audit_log (user_id, action, route, request_id, client_ip, outcome, payload_hash, token_hash, details)
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	_, err := r.store.dbPostgres.Exec(ctx, query,
+		data.UserId,
+		data.Action,
+		data.Route,
+		data.RequestId,
+		data.ClientIP,
+		data.Outcome,
+		data.PayloadHash,
+		data.TokenHash,
+		data.Details,
+	)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	return nil
+
+}
+
+//query audit log in postgres, newest first
+func (r *DataRepository) QueryAuditPostgres(filter model.AuditFilter) ([]model.AuditEvent, error) {
+
+	query := `
+	select user_id, action, route, request_id, client_ip, outcome, payload_hash, token_hash, details, created_at
+	from audit_log where true`
+	args := []interface{}{}
+
+	arg := func(cond string, v interface{}) {
+		args = append(args, v)
+		query += fmt.Sprintf(" and "+cond, len(args))
+	}
+	if filter.UserId != nil {
+		arg("user_id = $%d", *filter.UserId)
+	}
+	if filter.RequestId != "" {
+		arg("request_id = $%d", filter.RequestId)
+	}
+	if !filter.From.IsZero() {
+		arg("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		arg("created_at < $%d", filter.To)
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by created_at desc, id desc limit $%d", len(args))
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+
+	rows, err := r.store.dbPostgres.Query(ctx, query, args...)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []model.AuditEvent{}
+	for rows.Next() {
+		e := model.AuditEvent{}
+		if err := rows.Scan(
+			&e.UserId,
+			&e.Action,
+			&e.Route,
+			&e.RequestId,
+			&e.ClientIP,
+			&e.Outcome,
+			&e.PayloadHash,
+			&e.TokenHash,
+			&e.Details,
+			&e.TimeEvent,
+		); err != nil {
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+
+	return events, nil
 
 }
@@ -0,0 +1,109 @@
+package sqlstore
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/migrate"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//store over migrated schema, TEST_POSTGRES_DSN of a disposable database
+func testStore(t *testing.T) *Store {
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	db, err := pgxpool.Connect(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	m, err := migrate.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(db, nil, &model.Service{})
+}
+
+func TestBookingRoundTrip(t *testing.T) {
+
+	s := testStore(t)
+	id := "rt-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	booking := model.DataBooking{
+		RequestId: id, ActionType: "bill", UniqModCode: 12345, Modification: "A21R33",
+		ModFamily: "ГАЗель NEXT", ModBodyType: "борт", ModEngine: "cummins", ModBase: "base", ModTuning: "none",
+		Vin: "X96A21R33K2000001", PriceWithNds: 2500000, TypeClient: "personal", Inn: "500100732259",
+		DeliveryAddressCode: "52", DeliveryAddress: "Нижний Новгород", Hid: "h1", Surname: "Иванов", Name: "Иван",
+		Patronymic: "Иванович", PassportSer: "4506", PassportNumber: "123456", Snils: "11223344595",
+		DateOfBirth: "1980-01-02", Email: "client@example.ru", PhoneNumber: "+79991234567", Comment: "comment",
+		Consentmailing: "yes", TimeRequest: "2026-01-02T15:04:05", BillNumber: "B-1", UrlMod: "https://example.ru",
+		Clientid: "ga", Ymuid: "ym", TestMod: true,
+	}
+	if err := s.Data().QueryInsertBookingPostgres(booking); err != nil {
+		t.Fatal(err)
+	}
+	status, err := s.Data().QueryBookingStatusPostgres(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Booking == nil || !reflect.DeepEqual(*status.Booking, booking) {
+		t.Errorf("booking read back %+v, want %+v", status.Booking, booking)
+	}
+
+	form := model.DataForms{RequestId: id + "-f", TimeRequest: "2026-01-02T15:04:05", FormName: "call", PriceWithNds: 100, TestMod: true}
+	if err := s.Data().QueryInsertFormsPostgres(form); err != nil {
+		t.Fatal(err)
+	}
+	status, err = s.Data().QueryBookingStatusPostgres(form.RequestId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Form == nil || !reflect.DeepEqual(*status.Form, form) {
+		t.Errorf("form read back %+v, want %+v", status.Form, form)
+	}
+}
+
+func TestAuthLockoutRoundTrip(t *testing.T) {
+
+	s := testStore(t)
+	key := "rt-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "@example.ru"
+	until := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+
+	if err := s.User().SaveAuthLockout(model.AuthLockout{Kind: model.LockoutEmail, Key: key, Until: until, Lockouts: 2}); err != nil {
+		t.Fatal(err)
+	}
+	l, err := s.User().FindAuthLockout(model.LockoutEmail, key)
+	if err != nil || !l.Until.Equal(until) || l.Lockouts != 2 {
+		t.Fatalf("lockout %+v, %v", l, err)
+	}
+	active, err := s.User().ListAuthLockouts(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, a := range active {
+		found = found || a.Key == key
+	}
+	if !found {
+		t.Errorf("active lockouts %v, want %s", active, key)
+	}
+	if ok, err := s.User().DeleteAuthLockout(model.LockoutEmail, key); !ok || err != nil {
+		t.Errorf("delete: %v, %v", ok, err)
+	}
+	if ok, _ := s.User().DeleteAuthLockout(model.LockoutEmail, key); ok {
+		t.Error("deleted twice")
+	}
+}
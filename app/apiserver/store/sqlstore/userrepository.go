@@ -2,14 +2,9 @@ package sqlstore
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
-	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/jackc/pgx/v4"
 	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
@@ -20,18 +15,13 @@ type UserRepository struct {
 	store *Store
 }
 
-type AccessDetails struct {
-	UserId uint64
-	Exp    uint64
-}
-
 //Find jwt email password (create token)
 func (r *UserRepository) FindUser(email string, password string) (*model.User1, error) {
 	u := &model.User1{}
 	if err := r.store.dbPostgres.QueryRow(context.Background(),
-		"SELECT id, email, password FROM users WHERE email = $1 AND password = $2",
+		"SELECT id, email, password FROM users WHERE email = $1 AND password = $2 AND NOT disabled",
 		email, password).Scan(&u.ID, &u.Email, &u.Password); err != nil {
-		if err == sql.ErrNoRows {
+		if err == pgx.ErrNoRows {
 			logger.ErrorLogger.Println(err)
 			return nil, store.ErrRecordNotFound
 		}
@@ -41,95 +31,216 @@ func (r *UserRepository) FindUser(email string, password string) (*model.User1,
 	return u, nil
 }
 
-//Find jwt user id (verify token)
-func (r *UserRepository) FindUserid(userid uint64) error {
-	u := &model.User2{}
+//Find jwt user id (verify token), role of active user
+func (r *UserRepository) FindUserid(userid uint64) (string, error) {
+	var role string
 
 	if err := r.store.dbPostgres.QueryRow(context.Background(),
-		"SELECT id FROM users WHERE id = $1",
-		userid).Scan(&u.UserID); err != nil {
-		if err == sql.ErrNoRows {
+		"SELECT role FROM users WHERE id = $1 AND NOT disabled",
+		userid).Scan(&role); err != nil {
+		if err == pgx.ErrNoRows {
 			logger.ErrorLogger.Println(err)
-			return store.ErrRecordNotFound
+			return "", store.ErrRecordNotFound
 		}
 
+		return "", err
+	}
+	return role, nil
+}
+
+//Find user role
+func (r *UserRepository) FindUserRole(userid uint64) (string, error) {
+	var role string
+
+	if err := r.store.dbPostgres.QueryRow(context.Background(),
+		"SELECT role FROM users WHERE id = $1",
+		userid).Scan(&role); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
+		return "", err
+	}
+	return role, nil
+}
+
+//update last login time
+func (r *UserRepository) UpdateLastLogin(userid uint64) error {
+	if _, err := r.store.dbPostgres.Exec(context.Background(),
+		"UPDATE users SET last_login = now() WHERE id = $1",
+		userid); err != nil {
+		logger.ErrorLogger.Println(err)
 		return err
 	}
 	return nil
 }
 
-//creating token
-//create token
-func (r *UserRepository) CreateToken(userid uint64, config *model.Service) (string, time.Time, error) {
-	var err error
+//create user, ErrRecordExists if email is taken
+func (r *UserRepository) CreateUser(u model.User) (uint64, error) {
+	var id uint64
+
+	if err := r.store.dbPostgres.QueryRow(context.Background(), `
+	insert into users (email, password, role)
+	values($1, $2, $3)
+	on conflict (email) do nothing
+	returning id`,
+		u.Email, u.Password, u.Role).Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, store.ErrRecordExists
+		}
+		logger.ErrorLogger.Println(err)
+		return 0, err
+	}
+	return id, nil
+}
 
-	datetime_exp_unix := time.Now().Add(time.Hour * 24 * time.Duration(config.Spec.Jwt.LifeTerm)).Unix()
-	datetime_exp := time.Unix(datetime_exp_unix, 0)
-	t := new(time.Time)
+//list users
+func (r *UserRepository) ListUsers() ([]model.User, error) {
 
-	atClaims := jwt.MapClaims{}
-	atClaims["authorized"] = true
-	atClaims["user_id"] = userid
-	atClaims["exp"] = datetime_exp_unix
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
-	token, err := at.SignedString([]byte(config.Spec.Jwt.TokenDecode))
+	rows, err := r.store.dbPostgres.Query(context.Background(),
+		"SELECT id, email, role, disabled, last_login, created_at FROM users ORDER BY id")
 	if err != nil {
 		logger.ErrorLogger.Println(err)
-		return "", *t, err
+		return nil, err
 	}
 
-	return token, datetime_exp, nil
-}
+	defer rows.Close()
 
-//extract token from header
-func (r *UserRepository) ExtractToken(req *http.Request) string {
-	bearToken := req.Header.Get("Authorization")
-	//normally Authorization the_token_xxx
-	strArr := strings.Split(bearToken, " ")
-	if len(strArr) == 2 {
-		return strArr[1]
+	users := []model.User{}
+	for rows.Next() {
+		u := model.User{}
+		if err := rows.Scan(&u.Id, &u.Email, &u.Role, &u.Disabled, &u.LastLogin, &u.CreatedAt); err != nil {
+			logger.ErrorLogger.Println(err)
+			return nil, err
+		}
+		users = append(users, u)
 	}
-	return bearToken
+
+	return users, rows.Err()
 }
 
-//verify token
-func (r *UserRepository) VerifyToken(req *http.Request, config *model.Service) (*jwt.Token, error) {
-	tokenString := r.ExtractToken(req)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		//Make sure that the token method conform to "SigningMethodHMAC"
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+//find user by id, disabled included
+func (r *UserRepository) FindUserById(userid uint64) (*model.User, error) {
+	u := &model.User{}
+
+	if err := r.store.dbPostgres.QueryRow(context.Background(),
+		"SELECT id, email, role, disabled, last_login, created_at FROM users WHERE id = $1",
+		userid).Scan(&u.Id, &u.Email, &u.Role, &u.Disabled, &u.LastLogin, &u.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, store.ErrRecordNotFound
 		}
-		return []byte(config.Spec.Jwt.TokenDecode), nil
-	})
-	if err != nil {
 		logger.ErrorLogger.Println(err)
 		return nil, err
 	}
-	return token, nil
+	return u, nil
 }
 
-//extract data from token
-func (r *UserRepository) ExtractTokenMetadata(req *http.Request, config *model.Service) (*model.AccessDetails, error) {
+//update user column, ErrRecordNotFound if user is missing
+func (r *UserRepository) updateUser(userid uint64, query string, value interface{}) error {
+	tag, err := r.store.dbPostgres.Exec(context.Background(), query, userid, value)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrRecordNotFound
+	}
+	return nil
+}
+
+//disable or enable user
+func (r *UserRepository) UpdateUserDisabled(userid uint64, disabled bool) error {
+	return r.updateUser(userid, "UPDATE users SET disabled = $2 WHERE id = $1", disabled)
+}
 
-	//var accessDetails model.AccessDetails
+//reset user password
+func (r *UserRepository) UpdateUserPassword(userid uint64, password string) error {
+	return r.updateUser(userid, "UPDATE users SET password = $2 WHERE id = $1", password)
+}
 
-	token, err := r.VerifyToken(req, config)
+//assign user role
+func (r *UserRepository) UpdateUserRole(userid uint64, role string) error {
+	return r.updateUser(userid, "UPDATE users SET role = $2 WHERE id = $1", role)
+}
+
+//delete user
+func (r *UserRepository) DeleteUser(userid uint64) error {
+	tag, err := r.store.dbPostgres.Exec(context.Background(), "DELETE FROM users WHERE id = $1", userid)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrRecordNotFound
+	}
+	return nil
+}
+
+//save auth lockout of key, expired lockouts are pruned
+func (r *UserRepository) SaveAuthLockout(l model.AuthLockout) error {
+
+	query := `
+	insert into auth_lockouts (kind, key, until, lockouts)
+	values($1, $2, $3, $4)
+	on conflict (kind, key) do update set until = excluded.until, lockouts = excluded.lockouts`
+
+	if _, err := r.store.dbPostgres.Exec(context.Background(), query, l.Kind, l.Key, l.Until, l.Lockouts); err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	if _, err := r.store.dbPostgres.Exec(context.Background(), "DELETE FROM auth_lockouts WHERE until < now()"); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+	return nil
+}
+
+//find auth lockout of key, expired included
+func (r *UserRepository) FindAuthLockout(kind string, key string) (*model.AuthLockout, error) {
+	l := &model.AuthLockout{}
+
+	if err := r.store.dbPostgres.QueryRow(context.Background(),
+		"SELECT kind, key, until, lockouts FROM auth_lockouts WHERE kind = $1 AND key = $2",
+		kind, key).Scan(&l.Kind, &l.Key, &l.Until, &l.Lockouts); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		logger.ErrorLogger.Println(err)
+		return nil, err
+	}
+	return l, nil
+}
+
+//list auth lockouts active at now
+func (r *UserRepository) ListAuthLockouts(now time.Time) ([]model.AuthLockout, error) {
+
+	rows, err := r.store.dbPostgres.Query(context.Background(),
+		"SELECT kind, key, until, lockouts FROM auth_lockouts WHERE until > $1 ORDER BY kind, key", now)
 	if err != nil {
 		logger.ErrorLogger.Println(err)
 		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok && token.Valid {
-		userId, err := strconv.ParseUint(fmt.Sprintf("%.f", claims["user_id"]), 10, 64)
-		if err != nil {
+	defer rows.Close()
+
+	lockouts := []model.AuthLockout{}
+	for rows.Next() {
+		l := model.AuthLockout{}
+		if err := rows.Scan(&l.Kind, &l.Key, &l.Until, &l.Lockouts); err != nil {
 			logger.ErrorLogger.Println(err)
 			return nil, err
 		}
-		return &model.AccessDetails{
-			UserId: userId,
-		}, nil
+		lockouts = append(lockouts, l)
+	}
+	return lockouts, rows.Err()
+}
+
+//delete auth lockout of key, false if there was none
+func (r *UserRepository) DeleteAuthLockout(kind string, key string) (bool, error) {
+	tag, err := r.store.dbPostgres.Exec(context.Background(), "DELETE FROM auth_lockouts WHERE kind = $1 AND key = $2", kind, key)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return false, err
 	}
-	return nil, err
+	return tag.RowsAffected() == 1, nil
 }
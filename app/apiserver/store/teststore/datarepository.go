@@ -0,0 +1,655 @@
+package teststore
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//mssql procedures, keys of scripted responses
+const (
+	ProcBooking       = "booking"
+	ProcBookingPaid   = "booking_paid"
+	ProcBookingCancel = "booking_cancel"
+)
+
+//default procedure response
+const RespMssqlOk = "Обработка данных прошла успешно"
+
+//Procedure scripted mssql response
+type Procedure struct {
+	Response string
+	Err      error
+}
+
+//Call recorded mssql procedure call
+type Call struct {
+	Procedure string
+	RequestId string
+}
+
+//Data repository in memory
+//seeds are returned by get methods, inserts are recorded,
+//lock while reading records concurrently with background workers (mailing, sagas)
+type DataRepository struct {
+	sync.Mutex
+	store *Store
+	//seeds
+	Stocks           []model.DataStocks
+	BasicModelsPrice []model.DataBasicModelsPrice
+	OptionsPrice     []model.DataOptionsPrice
+	GeneralPrice     []model.DataGeneralPrice
+	Sprav            []model.DataSprav
+	Options          []model.DataOptions
+	OptionsSprav     []model.DataOptionsSprav
+	Packets          []model.DataPackets
+	Colors           []model.DataColors
+	//errors returned by method name, e.g. "QueryInsertBookingPostgres"
+	Errors map[string]error
+	//records
+	Calls           []Call
+	Bookings        []model.DataBooking
+	Forms           []model.DataForms
+	LeadGets        []model.DataLeadGet
+	WorkLists       []model.DataWorkList
+	Statuses        []model.DataStatuses
+	Results         map[string][]model.ResponseBooking
+	Transitions     []model.LeadTransition
+	MailingStatuses []model.MailingStatus
+	Cancellations   []model.Cancellation
+	Audit           []model.AuditEvent
+	//state
+	scripts            map[string][]Procedure
+	leadStates         map[string]*model.LeadState
+	payments           map[string]*model.Payment
+	bills              map[string]bill
+	attachments        map[string]*model.Attachment
+	bookingAttachments map[string][]string
+	sagas              map[string]*saga
+}
+
+type bill struct {
+	number string
+	pdf    []byte
+}
+
+type saga struct {
+	model.BookingSaga
+	updated time.Time
+}
+
+func newDataRepository(s *Store) *DataRepository {
+	return &DataRepository{
+		store:              s,
+		Errors:             make(map[string]error),
+		Results:            make(map[string][]model.ResponseBooking),
+		scripts:            make(map[string][]Procedure),
+		leadStates:         make(map[string]*model.LeadState),
+		payments:           make(map[string]*model.Payment),
+		bills:              make(map[string]bill),
+		attachments:        make(map[string]*model.Attachment),
+		bookingAttachments: make(map[string][]string),
+		sagas:              make(map[string]*saga),
+	}
+}
+
+//Script queues responses of procedure, RespMssqlOk when queue is empty
+func (r *DataRepository) Script(procedure string, responses ...Procedure) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.scripts[procedure] = append(r.scripts[procedure], responses...)
+}
+
+//Attachments linked to booking
+func (r *DataRepository) BookingAttachments(requestId string) []string {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]string(nil), r.bookingAttachments[requestId]...)
+}
+
+//Payment by id, nil if missing
+func (r *DataRepository) Payment(paymentId string) *model.Payment {
+	r.Lock()
+	defer r.Unlock()
+
+	if p, ok := r.payments[paymentId]; ok {
+		c := *p
+		return &c
+	}
+	return nil
+}
+
+//call procedure, locked
+func (r *DataRepository) call(procedure string, requestId string) (string, error) {
+	r.Calls = append(r.Calls, Call{Procedure: procedure, RequestId: requestId})
+	queue := r.scripts[procedure]
+	if len(queue) == 0 {
+		return RespMssqlOk, nil
+	}
+	r.scripts[procedure] = queue[1:]
+	return queue[0].Response, queue[0].Err
+}
+
+func (r *DataRepository) QueryInsertMssql(data model.DataBooking) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := data.ValidateDataBooking(); err != nil {
+		return "", err
+	}
+	return r.call(ProcBooking, data.RequestId)
+}
+
+func (r *DataRepository) QueryInsertBookingPostgres(data model.DataBooking) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertBookingPostgres"]; err != nil {
+		return err
+	}
+	r.Bookings = append(r.Bookings, data)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertFormsPostgres(data model.DataForms) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertFormsPostgres"]; err != nil {
+		return err
+	}
+	r.Forms = append(r.Forms, data)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertLeadGetPostgres(data model.DataLeadGet) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertLeadGetPostgres"]; err != nil {
+		return err
+	}
+	r.LeadGets = append(r.LeadGets, data)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertWorkListsPostgres(data model.DataWorkList) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertWorkListsPostgres"]; err != nil {
+		return err
+	}
+	r.WorkLists = append(r.WorkLists, data)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertStatusesPostgres(data model.DataStatuses) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertStatusesPostgres"]; err != nil {
+		return err
+	}
+	r.Statuses = append(r.Statuses, data)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertBookingResultPostgres(requestId string, data model.ResponseBooking) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.Results[requestId] = append(r.Results[requestId], data)
+	return nil
+}
+
+//booking status built from records, same shape as sqlstore
+func (r *DataRepository) QueryBookingStatusPostgres(requestId string) (*model.BookingStatus, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryBookingStatusPostgres"]; err != nil {
+		return nil, err
+	}
+
+	status := &model.BookingStatus{
+		RequestId: requestId,
+		Timeline:  []model.BookingEvent{},
+	}
+
+	for i := range r.Bookings {
+		if r.Bookings[i].RequestId == requestId {
+			b := r.Bookings[i]
+			status.Booking = &b
+			break
+		}
+	}
+	if status.Booking == nil {
+		for i := range r.Forms {
+			if r.Forms[i].RequestId == requestId {
+				f := r.Forms[i]
+				status.Form = &f
+				break
+			}
+		}
+	}
+	if status.Booking == nil && status.Form == nil {
+		return nil, store.ErrRecordNotFound
+	}
+
+	if results := r.Results[requestId]; len(results) > 0 {
+		last := results[len(results)-1]
+		status.Result = &model.BookingResult{
+			StatusMs:       last.StatusMs,
+			ResponseMs:     last.ResponseMs,
+			StatusGazCrm:   last.StatusGazCrm,
+			ResponseGazCrm: last.ResponseGazCrm,
+		}
+	}
+
+	clients := make(map[string]bool)
+	for _, e := range r.LeadGets {
+		if e.Data.RequestId.RequestId == requestId {
+			status.Timeline = append(status.Timeline, model.BookingEvent{
+				TimeEvent: e.Data.TimeRequest.TimeRequest,
+				Source:    "lead_get",
+				EventName: e.Data.EventName.EventName,
+			})
+		}
+	}
+	for _, e := range r.Statuses {
+		if e.Data.RequestId.RequestId == requestId {
+			status.Timeline = append(status.Timeline, model.BookingEvent{
+				TimeEvent:        e.Data.TimeRequest.TimeRequest,
+				Source:           "status",
+				EventName:        e.Data.EventName.EventName,
+				GazcrmClientId:   e.Data.GazcrmClientId.GazcrmClientId,
+				GazCrmWorkListId: e.Data.GazCrmWorkListId.GazCrmWorkListId,
+			})
+			if e.Data.GazcrmClientId.GazcrmClientId != "" {
+				clients[e.Data.GazcrmClientId.GazcrmClientId] = true
+			}
+		}
+	}
+	for _, e := range r.WorkLists {
+		if clients[e.Data.GazcrmClientId.GazcrmClientId] {
+			status.Timeline = append(status.Timeline, model.BookingEvent{
+				TimeEvent:        e.Data.TimeRequest.TimeRequest,
+				Source:           "work_list",
+				EventName:        e.Data.EventName.EventName,
+				GazcrmClientId:   e.Data.GazcrmClientId.GazcrmClientId,
+				GazCrmWorkListId: e.Data.GazCrmWorkListId.GazCrmWorkListId,
+			})
+		}
+	}
+	sort.SliceStable(status.Timeline, func(i, j int) bool {
+		return status.Timeline[i].TimeEvent < status.Timeline[j].TimeEvent
+	})
+
+	if state, ok := r.leadStates[requestId]; ok {
+		c := *state
+		status.LeadState = &c
+	}
+	if s, ok := r.sagas[requestId]; ok {
+		c := s.BookingSaga
+		c.Steps = append([]model.SagaStep(nil), s.Steps...)
+		status.Saga = &c
+	}
+
+	return status, nil
+}
+
+func (r *DataRepository) QueryApplyLeadEventPostgres(requestId string, event model.LeadEvent) (*model.LeadTransition, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	t := model.ApplyLeadEvent(requestId, r.leadStates[requestId], event, r.store.config.Spec.Lead.Events)
+	r.Transitions = append(r.Transitions, *t)
+	if t.Accepted {
+		r.leadStates[requestId] = &model.LeadState{
+			RequestId: requestId,
+			State:     t.ToState,
+			TimeEvent: t.TimeEvent,
+		}
+	}
+	return t, nil
+}
+
+func (r *DataRepository) QueryLeadStatePostgres(requestId string) (*model.LeadState, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	state, ok := r.leadStates[requestId]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+	c := *state
+	return &c, nil
+}
+
+func (r *DataRepository) QueryRequestIdByGazCrmClientPostgres(gazcrmClientId string) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	for i := len(r.Statuses) - 1; i >= 0; i-- {
+		d := r.Statuses[i].Data
+		if d.GazcrmClientId.GazcrmClientId == gazcrmClientId && d.RequestId.RequestId != "" {
+			return d.RequestId.RequestId, nil
+		}
+	}
+	return "", store.ErrRecordNotFound
+}
+
+func (r *DataRepository) QueryStocksMssql() ([]model.DataStocks, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.Stocks, r.Errors["QueryStocksMssql"]
+}
+
+func (r *DataRepository) QueryBasicModelsPriceMssql() ([]model.DataBasicModelsPrice, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.BasicModelsPrice, r.Errors["QueryBasicModelsPriceMssql"]
+}
+
+func (r *DataRepository) QueryOptionsPriceMssql() ([]model.DataOptionsPrice, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.OptionsPrice, r.Errors["QueryOptionsPriceMssql"]
+}
+
+func (r *DataRepository) QueryGeneralPriceMssql() ([]model.DataGeneralPrice, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.GeneralPrice, r.Errors["QueryGeneralPriceMssql"]
+}
+
+func (r *DataRepository) QuerySprav() ([]model.DataSprav, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.Sprav, r.Errors["QuerySprav"]
+}
+
+func (r *DataRepository) QueryOptionsData() ([]model.DataOptions, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.Options, r.Errors["QueryOptionsData"]
+}
+
+func (r *DataRepository) QueryOptionsDataSprav() ([]model.DataOptionsSprav, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.OptionsSprav, r.Errors["QueryOptionsDataSprav"]
+}
+
+func (r *DataRepository) QueryPacketsData() ([]model.DataPackets, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.Packets, r.Errors["QueryPacketsData"]
+}
+
+func (r *DataRepository) QueryColorsData() ([]model.DataColors, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.Colors, r.Errors["QueryColorsData"]
+}
+
+func (r *DataRepository) QueryInsertPaymentPostgres(data model.Payment) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertPaymentPostgres"]; err != nil {
+		return err
+	}
+	r.payments[data.PaymentId] = &data
+	return nil
+}
+
+func (r *DataRepository) QueryPaymentPostgres(paymentId string) (*model.Payment, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	p, ok := r.payments[paymentId]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+	c := *p
+	return &c, nil
+}
+
+func (r *DataRepository) QueryBookingPaymentsPostgres(requestId string) ([]model.Payment, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryBookingPaymentsPostgres"]; err != nil {
+		return nil, err
+	}
+	payments := []model.Payment{}
+	for _, p := range r.payments {
+		if p.RequestId == requestId {
+			payments = append(payments, *p)
+		}
+	}
+	return payments, nil
+}
+
+func (r *DataRepository) QueryUpdatePaymentStatusPostgres(paymentId string, status string) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	p, ok := r.payments[paymentId]
+	if !ok || p.Status != model.PaymentPending {
+		return false, nil
+	}
+	p.Status = status
+	return true, nil
+}
+
+func (r *DataRepository) QueryPaidMssql(data model.Payment) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.call(ProcBookingPaid, data.RequestId)
+}
+
+func (r *DataRepository) QueryInsertBillPostgres(requestId string, billNumber string, pdf []byte) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.bills[requestId]; !ok {
+		r.bills[requestId] = bill{number: billNumber, pdf: pdf}
+	}
+	return nil
+}
+
+func (r *DataRepository) QueryBillPostgres(requestId string) (string, []byte, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	b, ok := r.bills[requestId]
+	if !ok {
+		return "", nil, store.ErrRecordNotFound
+	}
+	return b.number, b.pdf, nil
+}
+
+func (r *DataRepository) QueryInsertMailingStatusPostgres(data model.MailingStatus) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.MailingStatuses = append(r.MailingStatuses, data)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertSagaPostgres(requestId string, userId uint64) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertSagaPostgres"]; err != nil {
+		return err
+	}
+	if _, ok := r.sagas[requestId]; ok {
+		return store.ErrRecordExists
+	}
+	r.sagas[requestId] = &saga{
+		BookingSaga: model.BookingSaga{
+			RequestId: requestId,
+			State:     model.SagaRunning,
+			UserId:    userId,
+			Steps:     []model.SagaStep{},
+		},
+		updated: time.Now(),
+	}
+	return nil
+}
+
+func (r *DataRepository) QueryUpdateSagaStatePostgres(requestId string, state string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if s, ok := r.sagas[requestId]; ok {
+		s.State = state
+		s.updated = time.Now()
+	}
+	return nil
+}
+
+func (r *DataRepository) QueryCompareSagaStatePostgres(requestId string, from string, to string) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	s, ok := r.sagas[requestId]
+	if !ok || s.State != from {
+		return false, nil
+	}
+	s.State = to
+	s.updated = time.Now()
+	return true, nil
+}
+
+func (r *DataRepository) QueryInsertSagaStepPostgres(requestId string, step model.SagaStep) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if s, ok := r.sagas[requestId]; ok {
+		step.TimeEvent = time.Now().Format("2006-01-02T15:04:05")
+		s.Steps = append(s.Steps, step)
+	}
+	return nil
+}
+
+func (r *DataRepository) QuerySagaPostgres(requestId string) (*model.BookingSaga, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	s, ok := r.sagas[requestId]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+	c := s.BookingSaga
+	c.Steps = append([]model.SagaStep(nil), s.Steps...)
+	return &c, nil
+}
+
+func (r *DataRepository) QueryClaimStaleSagasPostgres(age time.Duration) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	ids := []string{}
+	for id, s := range r.sagas {
+		if (s.State == model.SagaRunning || s.State == model.SagaRecovering) && time.Since(s.updated) > age {
+			s.State = model.SagaRecovering
+			s.updated = time.Now()
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (r *DataRepository) QueryCancelMssql(data model.DataBooking, reason string) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.call(ProcBookingCancel, data.RequestId)
+}
+
+func (r *DataRepository) QueryInsertCancellationPostgres(data model.Cancellation) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.Cancellations = append(r.Cancellations, data)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertAttachmentPostgres(data model.Attachment) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.Errors["QueryInsertAttachmentPostgres"]; err != nil {
+		return err
+	}
+	r.attachments[data.Id] = &data
+	return nil
+}
+
+func (r *DataRepository) QueryAttachmentPostgres(id string) (*model.Attachment, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	a, ok := r.attachments[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+	c := *a
+	return &c, nil
+}
+
+func (r *DataRepository) QueryInsertBookingAttachmentsPostgres(requestId string, ids []string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.bookingAttachments[requestId] = append(r.bookingAttachments[requestId], ids...)
+	return nil
+}
+
+func (r *DataRepository) QueryInsertAuditPostgres(data model.AuditEvent) error {
+	r.Lock()
+	defer r.Unlock()
+
+	data.TimeEvent = time.Now()
+	r.Audit = append(r.Audit, data)
+	return nil
+}
+
+func (r *DataRepository) QueryAuditPostgres(filter model.AuditFilter) ([]model.AuditEvent, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	events := []model.AuditEvent{}
+	for i := len(r.Audit) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		e := r.Audit[i]
+		if (filter.UserId != nil && e.UserId != *filter.UserId) ||
+			(filter.RequestId != "" && e.RequestId != filter.RequestId) ||
+			(!filter.From.IsZero() && e.TimeEvent.Before(filter.From)) ||
+			(!filter.To.IsZero() && !e.TimeEvent.Before(filter.To)) {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
@@ -0,0 +1,47 @@
+package teststore
+
+import (
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//Store in memory, for handler tests
+type Store struct {
+	config         *model.Service
+	userRepository *UserRepository
+	dataRepository *DataRepository
+}
+
+//New store, config gives lead events for lead state transitions
+func New(config *model.Service) *Store {
+	s := &Store{
+		config: config,
+	}
+	s.userRepository = &UserRepository{
+		store:    s,
+		users:    make(map[uint64]*user),
+		lockouts: make(map[string]model.AuthLockout),
+	}
+	s.dataRepository = newDataRepository(s)
+	return s
+}
+
+//User
+func (s *Store) User() store.UserRepository {
+	return s.userRepository
+}
+
+//Data
+func (s *Store) Data() store.DataRepository {
+	return s.dataRepository
+}
+
+//Users repository with seeding
+func (s *Store) Users() *UserRepository {
+	return s.userRepository
+}
+
+//Records data repository with seeds, scripts and recorded inserts
+func (s *Store) Records() *DataRepository {
+	return s.dataRepository
+}
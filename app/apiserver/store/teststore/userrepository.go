@@ -0,0 +1,223 @@
+package teststore
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+type user struct {
+	model.User
+	password string
+}
+
+//User repository in memory
+type UserRepository struct {
+	store    *Store
+	mu       sync.Mutex
+	users    map[uint64]*user
+	lastId   uint64
+	lockouts map[string]model.AuthLockout //by kind and key
+}
+
+//Seed user, returns user id
+func (r *UserRepository) Seed(email string, password string, role string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.add(model.User{Email: email, Role: role}, password)
+}
+
+//add user, locked
+func (r *UserRepository) add(u model.User, password string) uint64 {
+	r.lastId++
+	u.Id = r.lastId
+	u.Password = ""
+	u.CreatedAt = time.Now()
+	r.users[r.lastId] = &user{
+		User:     u,
+		password: password,
+	}
+	return r.lastId
+}
+
+//Find jwt email password (create token)
+func (r *UserRepository) FindUser(email string, password string) (*model.User1, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Email == email && u.password == password && !u.Disabled {
+			return &model.User1{ID: int(u.Id), Email: u.Email, Password: u.password}, nil
+		}
+	}
+	return nil, store.ErrRecordNotFound
+}
+
+//Find jwt user id (verify token), role of active user
+func (r *UserRepository) FindUserid(userid uint64) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userid]
+	if !ok || u.Disabled {
+		return "", store.ErrRecordNotFound
+	}
+	return u.Role, nil
+}
+
+//Find user role
+func (r *UserRepository) FindUserRole(userid uint64) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userid]
+	if !ok {
+		return "", store.ErrRecordNotFound
+	}
+	return u.Role, nil
+}
+
+//update last login time
+func (r *UserRepository) UpdateLastLogin(userid uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u, ok := r.users[userid]; ok {
+		now := time.Now()
+		u.LastLogin = &now
+	}
+	return nil
+}
+
+//create user, ErrRecordExists if email is taken
+func (r *UserRepository) CreateUser(u model.User) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.users {
+		if e.Email == u.Email {
+			return 0, store.ErrRecordExists
+		}
+	}
+	return r.add(u, u.Password), nil
+}
+
+//list users
+func (r *UserRepository) ListUsers() ([]model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := []model.User{}
+	for _, u := range r.users {
+		users = append(users, u.User)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Id < users[j].Id
+	})
+	return users, nil
+}
+
+//find user by id
+func (r *UserRepository) FindUserById(userid uint64) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userid]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+	c := u.User
+	return &c, nil
+}
+
+//update user, locked
+func (r *UserRepository) update(userid uint64, f func(u *user)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userid]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+	f(u)
+	return nil
+}
+
+//disable or enable user
+func (r *UserRepository) UpdateUserDisabled(userid uint64, disabled bool) error {
+	return r.update(userid, func(u *user) { u.Disabled = disabled })
+}
+
+//reset user password
+func (r *UserRepository) UpdateUserPassword(userid uint64, password string) error {
+	return r.update(userid, func(u *user) { u.password = password })
+}
+
+//assign user role
+func (r *UserRepository) UpdateUserRole(userid uint64, role string) error {
+	return r.update(userid, func(u *user) { u.Role = role })
+}
+
+//delete user
+func (r *UserRepository) DeleteUser(userid uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userid]; !ok {
+		return store.ErrRecordNotFound
+	}
+	delete(r.users, userid)
+	return nil
+}
+
+//save auth lockout of key
+func (r *UserRepository) SaveAuthLockout(l model.AuthLockout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lockouts[l.Kind+" "+l.Key] = l
+	return nil
+}
+
+//find auth lockout of key
+func (r *UserRepository) FindAuthLockout(kind string, key string) (*model.AuthLockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.lockouts[kind+" "+key]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+	return &l, nil
+}
+
+//list auth lockouts active at now
+func (r *UserRepository) ListAuthLockouts(now time.Time) ([]model.AuthLockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lockouts := []model.AuthLockout{}
+	for _, l := range r.lockouts {
+		if l.Until.After(now) {
+			lockouts = append(lockouts, l)
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].Kind+" "+lockouts[i].Key < lockouts[j].Kind+" "+lockouts[j].Key
+	})
+	return lockouts, nil
+}
+
+//delete auth lockout of key
+func (r *UserRepository) DeleteAuthLockout(kind string, key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.lockouts[kind+" "+key]
+	delete(r.lockouts, kind+" "+key)
+	return ok, nil
+}
@@ -0,0 +1,118 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//errors
+var (
+	ErrInvalid = errors.New("invalid token")
+)
+
+//Claims of access token
+type Claims struct {
+	UserId uint64
+	Exp    time.Time
+}
+
+//Issuer creates access tokens
+type Issuer interface {
+	Issue(userId uint64) (string, time.Time, error)
+}
+
+//Verifier checks access tokens
+type Verifier interface {
+	Verify(token string) (*Claims, error)
+}
+
+//Service issues and verifies tokens
+type Service interface {
+	Issuer
+	Verifier
+}
+
+//JWT hs256 tokens, claims authorized, user_id, exp
+type JWT struct {
+	secret   []byte
+	lifetime time.Duration
+}
+
+//New jwt service from config, lifetime in days
+func New(config *model.Service) *JWT {
+	return &JWT{
+		secret:   []byte(config.Spec.Jwt.TokenDecode),
+		lifetime: time.Hour * 24 * time.Duration(config.Spec.Jwt.LifeTerm),
+	}
+}
+
+//Issue token of user
+func (j *JWT) Issue(userId uint64) (string, time.Time, error) {
+
+	exp := time.Unix(time.Now().Add(j.lifetime).Unix(), 0)
+
+	claims := jwt.MapClaims{}
+	claims["authorized"] = true
+	claims["user_id"] = userId
+	claims["exp"] = exp.Unix()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, exp, nil
+}
+
+//Verify token signature and expiration, hs256 tokens with exp only
+func (j *JWT) Verify(tokenString string) (*Claims, error) {
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		//tokens are issued with hs256 only
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return j.secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalid
+	}
+
+	userId, err := strconv.ParseUint(fmt.Sprintf("%.f", claims["user_id"]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	//jwt validation skips absent exp, such token would never expire
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, ErrInvalid
+	}
+
+	return &Claims{
+		UserId: userId,
+		Exp:    time.Unix(int64(exp), 0),
+	}, nil
+}
+
+//FromRequest token of Authorization header, "Bearer token" or bare token
+func FromRequest(r *http.Request) string {
+	bearToken := r.Header.Get("Authorization")
+	strArr := strings.Split(bearToken, " ")
+	if len(strArr) == 2 {
+		return strArr[1]
+	}
+	return bearToken
+}
@@ -0,0 +1,88 @@
+package token
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+func newJWT(secret string) *JWT {
+	config := &model.Service{}
+	config.Spec.Jwt.TokenDecode = secret
+	config.Spec.Jwt.LifeTerm = 1
+	return New(config)
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestIssueVerify(t *testing.T) {
+
+	j := newJWT("secret")
+	token, exp, err := j.Issue(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(exp); d < 23*time.Hour || d > 24*time.Hour {
+		t.Errorf("exp in %v, want one day", d)
+	}
+
+	claims, err := j.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserId != 42 || !claims.Exp.Equal(exp) {
+		t.Errorf("claims %+v, want user 42 exp %v", claims, exp)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+
+	j := newJWT("secret")
+	future := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", sign(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(-time.Minute).Unix()})},
+		{"missing exp", sign(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"user_id": 1})},
+		{"string exp", sign(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"user_id": 1, "exp": "never"})},
+		{"wrong key", sign(t, jwt.SigningMethodHS256, []byte("other"), jwt.MapClaims{"user_id": 1, "exp": future})},
+		{"wrong alg hs512", sign(t, jwt.SigningMethodHS512, []byte("secret"), jwt.MapClaims{"user_id": 1, "exp": future})},
+		{"wrong alg none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{"user_id": 1, "exp": future})},
+		{"missing user", sign(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"exp": future})},
+		{"malformed", "not.a.token"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		if claims, err := j.Verify(tt.token); err == nil {
+			t.Errorf("%s: verified %+v", tt.name, claims)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+
+	tests := map[string]string{
+		"Bearer abc": "abc",
+		"abc":        "abc",
+		"":           "",
+	}
+	for header, want := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", header)
+		if got := FromRequest(r); got != want {
+			t.Errorf("%q: %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package apiserver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/sqlstore"
+)
+
+//users cli usage, passwords are read from first line of stdin, not from arguments
+const usersUsage = `usage: apiserver users list
+       apiserver users create <email> [role]     < password
+       apiserver users disable|enable|delete <id>
+       apiserver users password <id>             < password
+       apiserver users role <id> <role>
+       apiserver users unlock email|ip <email or ip>`
+
+//Users administration from command line, changes are audited with route cli
+func Users(config *model.Service, args []string, in io.Reader, out io.Writer) error {
+
+	if len(args) == 0 {
+		return errors.New(usersUsage)
+	}
+
+	dbPostgres, err := newDbPostgres(config)
+	if err != nil {
+		logger.ErrorLogger.Println(err)
+		return err
+	}
+
+	defer dbPostgres.Close()
+
+	return users(sqlstore.New(dbPostgres, nil, config), args, in, out)
+}
+
+//read password of first input line
+func readPassword(in io.Reader) (string, error) {
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is required on stdin")
+	}
+	return password, nil
+}
+
+//users command over store
+func users(st store.Store, args []string, in io.Reader, out io.Writer) error {
+
+	if len(args) == 0 {
+		return errors.New(usersUsage)
+	}
+
+	admin := &userAdmin{store: st}
+	actor := auditActor{Route: "cli"}
+
+	//argument count
+	need := func(n int) error {
+		if len(args) < n {
+			return errors.New(usersUsage)
+		}
+		return nil
+	}
+	//argument count and user id of first argument
+	needId := func(n int) (uint64, error) {
+		if err := need(n); err != nil {
+			return 0, err
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("user id %q: %v", args[1], err)
+		}
+		return id, nil
+	}
+
+	switch args[0] {
+	case "list":
+		users, err := st.User().ListUsers()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tEMAIL\tROLE\tDISABLED\tLAST LOGIN")
+		for _, u := range users {
+			lastLogin := "-"
+			if u.LastLogin != nil {
+				lastLogin = u.LastLogin.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%t\t%s\n", u.Id, u.Email, u.Role, u.Disabled, lastLogin)
+		}
+		return tw.Flush()
+	case "create":
+		if err := need(2); err != nil {
+			return err
+		}
+		password, err := readPassword(in)
+		if err != nil {
+			return err
+		}
+		u := model.User{Email: args[1], Password: password}
+		if len(args) > 2 {
+			u.Role = args[2]
+		}
+		created, err := admin.create(actor, u)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "user %d created\n", created.Id)
+		return nil
+	case "disable", "enable":
+		id, err := needId(2)
+		if err != nil {
+			return err
+		}
+		if err := admin.setDisabled(actor, id, args[0] == "disable"); err != nil {
+			return err
+		}
+	case "delete":
+		id, err := needId(2)
+		if err != nil {
+			return err
+		}
+		if err := admin.delete(actor, id); err != nil {
+			return err
+		}
+	case "password":
+		id, err := needId(2)
+		if err != nil {
+			return err
+		}
+		password, err := readPassword(in)
+		if err != nil {
+			return err
+		}
+		if err := admin.setPassword(actor, id, password); err != nil {
+			return err
+		}
+	case "role":
+		id, err := needId(3)
+		if err != nil {
+			return err
+		}
+		if err := admin.setRole(actor, id, args[2]); err != nil {
+			return err
+		}
+	case "unlock":
+		if err := need(3); err != nil {
+			return err
+		}
+		kind, key := args[1], args[2]
+		switch kind {
+		case model.LockoutEmail:
+			key = authLimitEmail(key)
+		case model.LockoutIp:
+		default:
+			return errors.New(usersUsage)
+		}
+		//running instances drop their lockout on next attempt of key
+		unlocked, err := st.User().DeleteAuthLockout(kind, key)
+		e := model.AuditEvent{
+			Action:  model.AuditAuthUnlock,
+			Route:   actor.Route,
+			Outcome: model.AuditOk,
+			Details: kind + "=" + key,
+		}
+		if err != nil {
+			e.Outcome = model.AuditError
+			e.Details += " error=" + err.Error()
+		}
+		if err := st.Data().QueryInsertAuditPostgres(e); err != nil {
+			logger.ErrorLogger.Println(err)
+		}
+		if err != nil {
+			return err
+		}
+		if !unlocked {
+			fmt.Fprintln(out, kind+" "+key+" is not locked")
+			return nil
+		}
+	default:
+		return errors.New(usersUsage)
+	}
+
+	fmt.Fprintln(out, "ok")
+	return nil
+}
@@ -0,0 +1,83 @@
+package apiserver
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store/teststore"
+)
+
+func TestUsersCli(t *testing.T) {
+
+	st := teststore.New(&model.Service{})
+	admin := st.Users().Seed("admin@example.ru", "password", model.RoleAdmin)
+
+	run := func(stdin string, args ...string) (string, error) {
+		var out bytes.Buffer
+		err := users(st, args, strings.NewReader(stdin), &out)
+		return out.String(), err
+	}
+
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		out   string
+		ok    bool
+	}{
+		{"create", "password1\n", []string{"create", "new@example.ru"}, "user 2 created\n", true},
+		{"create admin", "password2", []string{"create", "second@example.ru", model.RoleAdmin}, "user 3 created\n", true},
+		{"create without password", "", []string{"create", "third@example.ru"}, "", false},
+		{"create short password", "short\n", []string{"create", "third@example.ru"}, "", false},
+		{"create without email", "password1\n", []string{"create"}, "", false},
+		{"list", "", []string{"list"}, "new@example.ru", true},
+		{"disable", "", []string{"disable", "2"}, "ok\n", true},
+		{"enable", "", []string{"enable", "2"}, "ok\n", true},
+		{"disable without id", "", []string{"disable"}, "", false},
+		{"disable bad id", "", []string{"disable", "new@example.ru"}, "", false},
+		{"password", "password3\r\n", []string{"password", "2"}, "ok\n", true},
+		{"password without input", "", []string{"password", "2"}, "", false},
+		{"role", "", []string{"role", "2", model.RoleAdmin}, "ok\n", true},
+		{"role without role", "", []string{"role", "2"}, "", false},
+		{"role unknown", "", []string{"role", "2", "root"}, "", false},
+		{"delete", "", []string{"delete", "3"}, "ok\n", true},
+		{"delete missing", "", []string{"delete", "30"}, "", false},
+		{"unknown command", "", []string{"drop"}, "", false},
+		{"no command", "", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		out, err := run(tt.stdin, tt.args...)
+		if (err == nil) != tt.ok || !strings.Contains(out, tt.out) {
+			t.Errorf("%s: %q, error %v", tt.name, out, err)
+		}
+	}
+
+	//password of stdin line is set, crlf trimmed
+	if _, err := st.User().FindUser("new@example.ru", "password3"); err != nil {
+		t.Errorf("password not changed: %v", err)
+	}
+	if role, _ := st.User().FindUserRole(2); role != model.RoleAdmin {
+		t.Errorf("role %s, want admin", role)
+	}
+	if _, err := st.User().FindUserById(3); err == nil {
+		t.Error("deleted user found")
+	}
+	if _, err := st.User().FindUserById(admin); err != nil {
+		t.Error(err)
+	}
+
+	//changes are audited with route cli
+	n := 0
+	for _, e := range st.Records().Audit {
+		if e.Route != "cli" {
+			t.Errorf("audit route %s, want cli", e.Route)
+		}
+		n++
+	}
+	if n == 0 {
+		t.Error("no audit records")
+	}
+}
@@ -0,0 +1,365 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/mux"
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/store"
+)
+
+//errors
+var (
+	errAdminOnly    = errors.New("admin role required")
+	errUserExists   = errors.New("user already exists")
+	errUserNotFound = errors.New("user not found")
+	errUserSelf     = errors.New("admin can't disable, delete or demote own account")
+	errUserId       = errors.New("user id must be a number")
+)
+
+//invalid user input
+type inputError struct {
+	err error
+}
+
+func (e inputError) Error() string {
+	return e.err.Error()
+}
+
+//acting user of audited change
+type auditActor struct {
+	UserId   uint64
+	Route    string
+	ClientIP string
+}
+
+//client ip of request, remote address is set to client of trusted proxies by realIP
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+//actor of admin request
+func requestActor(r *http.Request) auditActor {
+	return auditActor{
+		UserId:   userId(r),
+		Route:    r.Method + " " + r.URL.Path,
+		ClientIP: clientIP(r),
+	}
+}
+
+//user administration, shared by admin api and cli, every change is audited
+type userAdmin struct {
+	store store.Store
+}
+
+//record change in audit log
+func (a *userAdmin) audit(actor auditActor, action string, target uint64, details string, err error) {
+	e := model.AuditEvent{
+		UserId:   actor.UserId,
+		Action:   action,
+		Route:    actor.Route,
+		ClientIP: actor.ClientIP,
+		Outcome:  model.AuditOk,
+		Details:  fmt.Sprintf("user_id=%d", target),
+	}
+	if details != "" {
+		e.Details += " " + details
+	}
+	if err != nil {
+		e.Outcome = model.AuditError
+		e.Details += " error=" + err.Error()
+	}
+	if err := a.store.Data().QueryInsertAuditPostgres(e); err != nil {
+		logger.ErrorLogger.Println(err)
+	}
+}
+
+//create user
+func (a *userAdmin) create(actor auditActor, u model.User) (*model.User, error) {
+
+	if u.Role == "" {
+		u.Role = model.RoleClient
+	}
+	if err := u.Validate(); err != nil {
+		return nil, inputError{err}
+	}
+
+	id, err := a.store.User().CreateUser(u)
+	a.audit(actor, model.AuditUserCreate, id, "email="+u.Email+" role="+u.Role, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.store.User().FindUserById(id)
+}
+
+//disable or enable user
+func (a *userAdmin) setDisabled(actor auditActor, id uint64, disabled bool) error {
+
+	action := model.AuditUserEnable
+	if disabled {
+		action = model.AuditUserDisable
+		if id == actor.UserId {
+			return errUserSelf
+		}
+	}
+
+	err := a.store.User().UpdateUserDisabled(id, disabled)
+	a.audit(actor, action, id, "", err)
+	return err
+}
+
+//reset password
+func (a *userAdmin) setPassword(actor auditActor, id uint64, password string) error {
+
+	if err := validation.Validate(password, validation.Required, validation.Length(8, 0)); err != nil {
+		return inputError{fmt.Errorf("password: %v", err)}
+	}
+
+	err := a.store.User().UpdateUserPassword(id, password)
+	a.audit(actor, model.AuditUserPassword, id, "", err)
+	return err
+}
+
+//assign role
+func (a *userAdmin) setRole(actor auditActor, id uint64, role string) error {
+
+	if err := validation.Validate(role, validation.Required, validation.In(model.RoleClient, model.RoleAdmin)); err != nil {
+		return inputError{fmt.Errorf("role: %v", err)}
+	}
+	if id == actor.UserId && role != model.RoleAdmin {
+		return errUserSelf
+	}
+
+	err := a.store.User().UpdateUserRole(id, role)
+	a.audit(actor, model.AuditUserRole, id, "role="+role, err)
+	return err
+}
+
+//delete user
+func (a *userAdmin) delete(actor auditActor, id uint64) error {
+
+	if id == actor.UserId {
+		return errUserSelf
+	}
+
+	err := a.store.User().DeleteUser(id)
+	a.audit(actor, model.AuditUserDelete, id, "", err)
+	return err
+}
+
+//Middleware admin role, after auth middleware
+func (s *server) middleWareAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if role := userRole(r); role != model.RoleAdmin {
+			s.error(w, r, http.StatusForbidden, errAdminOnly)
+			logger.ErrorLogger.Println(errAdminOnly, userId(r), role)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+
+	})
+}
+
+//write user administration error
+func (s *server) userError(w http.ResponseWriter, r *http.Request, err error) {
+
+	var input inputError
+
+	switch {
+	case errors.As(err, &input):
+		s.error(w, r, http.StatusBadRequest, err)
+	case err == errUserSelf:
+		s.error(w, r, http.StatusBadRequest, err)
+	case err == store.ErrRecordExists:
+		s.error(w, r, http.StatusConflict, errUserExists)
+	case err == store.ErrRecordNotFound:
+		s.error(w, r, http.StatusNotFound, errUserNotFound)
+	default:
+		s.error(w, r, http.StatusInternalServerError, errPostgres)
+	}
+	logger.ErrorLogger.Println(err)
+}
+
+//user id of route
+func (s *server) routeUserId(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil {
+		s.error(w, r, http.StatusBadRequest, errUserId)
+		logger.ErrorLogger.Println(err)
+		return 0, false
+	}
+	return id, true
+}
+
+//handle list users
+func (s *server) handleListUsers() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		users, err := s.store.User().ListUsers()
+		if err != nil {
+			s.userError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, users)
+
+	}
+
+}
+
+//handle get user
+func (s *server) handleUser() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, ok := s.routeUserId(w, r)
+		if !ok {
+			return
+		}
+
+		u, err := s.store.User().FindUserById(id)
+		if err != nil {
+			s.userError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, u)
+
+	}
+
+}
+
+//handle create user, {"email": "", "password": "", "role": "client"}
+func (s *server) handleCreateUser() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := model.User{}
+
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+
+		u, err := s.users.create(requestActor(r), req)
+		if err != nil {
+			s.userError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, u)
+		logger.InfoLogger.Println("user created " + u.Email)
+
+	}
+
+}
+
+//handle disable or enable user
+func (s *server) handleDisableUser(disabled bool) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, ok := s.routeUserId(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.users.setDisabled(requestActor(r), id, disabled); err != nil {
+			s.userError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, newResponse("Ok", "user updated"))
+
+	}
+
+}
+
+//handle reset password, {"password": ""}
+func (s *server) handleUserPassword() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, ok := s.routeUserId(w, r)
+		if !ok {
+			return
+		}
+
+		req := model.User{}
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+
+		if err := s.users.setPassword(requestActor(r), id, req.Password); err != nil {
+			s.userError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, newResponse("Ok", "user updated"))
+
+	}
+
+}
+
+//handle assign role, {"role": "admin"}
+func (s *server) handleUserRole() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, ok := s.routeUserId(w, r)
+		if !ok {
+			return
+		}
+
+		req := model.User{}
+		if err := s.decode(r, &req); err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+
+		if err := s.users.setRole(requestActor(r), id, req.Role); err != nil {
+			s.userError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, newResponse("Ok", "user updated"))
+
+	}
+
+}
+
+//handle delete user
+func (s *server) handleDeleteUser() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, ok := s.routeUserId(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.users.delete(requestActor(r), id); err != nil {
+			s.userError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, newResponse("Ok", "user deleted"))
+
+	}
+
+}
@@ -0,0 +1,162 @@
+package apiserver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	logger "github.com/webdevolegkuprianov/server_http_rest/app/apiserver/logger"
+)
+
+//webhook headers
+//signature = hex(hmac_sha256(secret, timestamp + "." + nonce + "." + body))
+const (
+	headerWebhookIntegration = "X-Webhook-Integration"
+	headerWebhookTimestamp   = "X-Webhook-Timestamp"
+	headerWebhookNonce       = "X-Webhook-Nonce"
+	headerWebhookSignature   = "X-Webhook-Signature"
+)
+
+//default timestamp tolerance and nonce lifetime
+const webhookWindow = 300 * time.Second
+
+//errors
+var (
+	errWebhookSignature = errors.New("webhook signature error")
+	errWebhookReplay    = errors.New("webhook replay")
+)
+
+//nonce cache for replay protection, nonces expire in order of arrival
+type nonceCache struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	queue  []seenNonce //ordered by time
+}
+
+type seenNonce struct {
+	nonce string
+	at    time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+//add nonce, false if nonce was seen within window,
+//expired nonces are dropped from the queue head, so add is O(1) amortized
+func (c *nonceCache) add(nonce string, now time.Time, window time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for n < len(c.queue) && now.Sub(c.queue[n].at) > window {
+		delete(c.nonces, c.queue[n].nonce)
+		n++
+	}
+	c.queue = c.queue[n:]
+
+	if _, ok := c.nonces[nonce]; ok {
+		return false
+	}
+	c.nonces[nonce] = now
+	c.queue = append(c.queue, seenNonce{nonce: nonce, at: now})
+
+	return true
+}
+
+//nonces within window
+func (c *nonceCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.nonces)
+}
+
+//sign webhook body
+func signWebhook(secret string, timestamp string, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//secret of integration from config
+func (s *server) webhookSecret(name string) (string, bool) {
+	for _, i := range s.config.Spec.Webhook.Integrations {
+		if i.Name == name && i.Secret != "" {
+			return i.Secret, true
+		}
+	}
+	return "", false
+}
+
+//Middleware webhook signature
+func (s *server) middleWareWebhook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		integration := r.Header.Get(headerWebhookIntegration)
+		auditIntegration(r, integration)
+
+		secret, ok := s.webhookSecret(integration)
+		if !ok {
+			s.error(w, r, http.StatusUnauthorized, errWebhookSignature)
+			logger.ErrorLogger.Println("unknown webhook integration: " + r.Header.Get(headerWebhookIntegration))
+			return
+		}
+
+		timestamp := r.Header.Get(headerWebhookTimestamp)
+		nonce := r.Header.Get(headerWebhookNonce)
+
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || nonce == "" {
+			s.error(w, r, http.StatusUnauthorized, errWebhookSignature)
+			logger.ErrorLogger.Println("webhook timestamp or nonce missing")
+			return
+		}
+
+		window := webhookWindow
+		if s.config.Spec.Webhook.Window > 0 {
+			window = time.Duration(s.config.Spec.Webhook.Window) * time.Second
+		}
+		now := time.Now()
+		if d := now.Sub(time.Unix(ts, 0)); d > window || d < -window {
+			s.error(w, r, http.StatusUnauthorized, errWebhookReplay)
+			logger.ErrorLogger.Println("webhook timestamp out of window")
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			s.bodyError(w, r, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		sign, err := hex.DecodeString(r.Header.Get(headerWebhookSignature))
+		expected, _ := hex.DecodeString(signWebhook(secret, timestamp, nonce, body))
+		if err != nil || !hmac.Equal(sign, expected) {
+			s.error(w, r, http.StatusUnauthorized, errWebhookSignature)
+			logger.ErrorLogger.Println("webhook signature mismatch")
+			return
+		}
+
+		//nonce is stored after signature check, unsigned requests can't fill the cache
+		if !s.nonces.add(nonce, now, window) {
+			s.error(w, r, http.StatusUnauthorized, errWebhookReplay)
+			logger.ErrorLogger.Println("webhook nonce reused: " + nonce)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+
+	})
+}
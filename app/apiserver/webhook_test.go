@@ -0,0 +1,113 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWebhook(t *testing.T) {
+
+	ts := newTestServer(t)
+	if err := yaml.Unmarshal([]byte("spec: {webhook: {window: 60, integrations: [{name: gazcrm, secret: hook secret}]}}"), ts.config); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"Data":{}}`
+	send := func(integration string, secret string, at time.Time, nonce string, body string) int {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		r := httptest.NewRequest("POST", "/webhook/requeststatus", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(headerWebhookIntegration, integration)
+		r.Header.Set(headerWebhookTimestamp, timestamp)
+		r.Header.Set(headerWebhookNonce, nonce)
+		r.Header.Set(headerWebhookSignature, signWebhook(secret, timestamp, nonce, []byte(body)))
+		w := httptest.NewRecorder()
+		ts.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name        string
+		integration string
+		secret      string
+		at          time.Time
+		nonce       string
+		body        string
+		code        int
+	}{
+		{"signed", "gazcrm", "hook secret", now, "n1", body, http.StatusOK},
+		{"replayed nonce", "gazcrm", "hook secret", now, "n1", body, http.StatusUnauthorized},
+		{"wrong secret", "gazcrm", "other secret", now, "n2", body, http.StatusUnauthorized},
+		{"unknown integration", "other", "hook secret", now, "n3", body, http.StatusUnauthorized},
+		{"no nonce", "gazcrm", "hook secret", now, "", body, http.StatusUnauthorized},
+		{"old timestamp", "gazcrm", "hook secret", now.Add(-61 * time.Second), "n4", body, http.StatusUnauthorized},
+		{"future timestamp", "gazcrm", "hook secret", now.Add(61 * time.Second), "n5", body, http.StatusUnauthorized},
+		{"timestamp within window", "gazcrm", "hook secret", now.Add(-50 * time.Second), "n6", body, http.StatusOK},
+		//signature of another nonce doesn't open a fresh one
+		{"rejected nonce is not stored", "gazcrm", "hook secret", now, "n2", body, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := send(tt.integration, tt.secret, tt.at, tt.nonce, tt.body); code != tt.code {
+			t.Errorf("%s: code %d, want %d", tt.name, code, tt.code)
+		}
+	}
+
+	//body changed after signing
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	r := httptest.NewRequest("POST", "/webhook/requeststatus", strings.NewReader(`{"Data":{"event_name":{"event_name":"x"}}}`))
+	r.Header.Set(headerWebhookIntegration, "gazcrm")
+	r.Header.Set(headerWebhookTimestamp, timestamp)
+	r.Header.Set(headerWebhookNonce, "n7")
+	r.Header.Set(headerWebhookSignature, signWebhook("hook secret", timestamp, "n7", []byte(body)))
+	w := httptest.NewRecorder()
+	ts.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("changed body: code %d", w.Code)
+	}
+
+	//every webhook call is audited with integration
+	audited := 0
+	for _, e := range ts.store.Records().Audit {
+		if e.Action == model.AuditRequest && e.Route == "POST /webhook/requeststatus" {
+			audited++
+			if !strings.Contains(e.Details, "integration=") {
+				t.Errorf("audit details %q", e.Details)
+			}
+		}
+	}
+	if audited != len(tests)+1 {
+		t.Errorf("%d webhook calls audited, want %d", audited, len(tests)+1)
+	}
+}
+
+func TestNonceCache(t *testing.T) {
+
+	c := newNonceCache()
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 100; i++ {
+		if !c.add(fmt.Sprint(i), now.Add(time.Duration(i)*time.Second), time.Minute) {
+			t.Fatalf("nonce %d rejected", i)
+		}
+	}
+	//nonces older than window are dropped on add
+	if c.size() != 61 {
+		t.Errorf("%d nonces kept, want 61", c.size())
+	}
+	at := now.Add(99 * time.Second)
+	if c.add("99", at, time.Minute) || c.add("39", at, time.Minute) {
+		t.Error("nonce within window accepted again")
+	}
+	if !c.add("38", at, time.Minute) {
+		t.Error("expired nonce rejected")
+	}
+}
@@ -0,0 +1,201 @@
+package gazcrmmock
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//Callback calls back server gaz crm routes
+//signed /webhook routes if secret is set, /auth routes with jwt otherwise
+type Callback struct {
+	Url         string        //server base url
+	Client      *http.Client  //http client
+	Step        time.Duration //delay between lifecycle events
+	Statuses    []string      //status event names after taking into work, last one closes the lead
+	Email       string        //server user to get token
+	Password    string        //server user password
+	Integration string        //webhook integration name
+	Secret      string        //webhook secret
+
+	mu    sync.Mutex
+	token string
+}
+
+//Lifecycle lead_get, status with gaz crm client id, work list, statuses
+func (c *Callback) Lifecycle(data model.DataGazCrmReq) {
+
+	requestId := data.RequestId.RequestId
+	clientId := strconv.FormatInt(rand.Int63(), 10)
+	workListId := strconv.FormatInt(rand.Int63(), 10)
+	at := time.Now()
+
+	next := func() string {
+		time.Sleep(c.Step)
+		at = at.Add(c.Step + time.Second)
+		return at.Format("2006-01-02T15:04:05")
+	}
+
+	leadGet := model.DataLeadGet{}
+	leadGet.Data.TimeRequest.TimeRequest = next()
+	leadGet.Data.EventName.EventName = "lead_get"
+	leadGet.Data.RequestId.RequestId = requestId
+	leadGet.Data.SubdivisionsId.SubdivisionsId = data.SubdivisionsId.SubdivisionsId
+	leadGet.Data.SubdivisionsName.SubdivisionsName = data.SubdivisionsName.SubdivisionsName
+	leadGet.Data.FormName.FormName = data.FormName.FormName
+	leadGet.Data.HostName.HostName = data.HostName.HostName
+	leadGet.Data.Division.Division = data.Division.Division
+	leadGet.Data.Area.Area = data.Area.Area
+	leadGet.Data.BrandName.BrandName = data.BrandName.BrandName
+	leadGet.Data.ClientID.ClientID = data.ClientID.ClientID
+	leadGet.Data.MetricsType.MetricsType = data.MetricsType.MetricsType
+	if err := c.post("/requestleadget", leadGet); err != nil {
+		log.Printf("%s: lead_get: %v", requestId, err)
+		return
+	}
+
+	status := func(eventName string) error {
+		s := model.DataStatuses{}
+		s.Data.TimeRequest.TimeRequest = next()
+		s.Data.EventName.EventName = eventName
+		s.Data.RequestId.RequestId = requestId
+		s.Data.GazcrmClientId.GazcrmClientId = clientId
+		s.Data.GazCrmWorkListId.GazCrmWorkListId = workListId
+		s.Data.ClientID.ClientID = data.ClientID.ClientID
+		s.Data.MetricsType.MetricsType = data.MetricsType.MetricsType
+		return c.post("/requeststatus", s)
+	}
+
+	//client id is known to server from statuses only, so the first status goes before work list
+	if len(c.Statuses) > 0 {
+		if err := status(c.Statuses[0]); err != nil {
+			log.Printf("%s: status: %v", requestId, err)
+			return
+		}
+	}
+
+	workList := model.DataWorkList{}
+	workList.Data.TimeRequest.TimeRequest = next()
+	workList.Data.EventName.EventName = "work_list"
+	workList.Data.GazcrmClientId.GazcrmClientId = clientId
+	workList.Data.GazCrmWorkListId.GazCrmWorkListId = workListId
+	if err := c.post("/requestworklist", workList); err != nil {
+		log.Printf("%s: work_list: %v", requestId, err)
+		return
+	}
+
+	for i := 1; i < len(c.Statuses); i++ {
+		if err := status(c.Statuses[i]); err != nil {
+			log.Printf("%s: status: %v", requestId, err)
+			return
+		}
+	}
+
+	log.Printf("%s: lifecycle done", requestId)
+}
+
+//post event to server
+func (c *Callback) post(route string, data interface{}) error {
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	prefix := "/auth"
+	if c.Secret != "" {
+		prefix = "/webhook"
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.Url+prefix+route, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.Secret != "" {
+		c.sign(req, body)
+	} else {
+		token, err := c.authenticate()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %d %s", route, resp.StatusCode, respBody)
+	}
+	log.Printf("%s: %s", route, respBody)
+
+	return nil
+}
+
+//sign request as in server webhook middleware
+func (c *Callback) sign(req *http.Request, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := strconv.FormatInt(rand.Int63(), 36)
+
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+
+	req.Header.Set("X-Webhook-Integration", c.Integration)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Nonce", nonce)
+	req.Header.Set("X-Webhook-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
+
+//token from /authentication, cached
+func (c *Callback) authenticate() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	body, err := json.Marshal(model.User1{Email: c.Email, Password: c.Password})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Client.Post(c.Url+"/authentication", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("authentication: %d", resp.StatusCode)
+	}
+
+	token := model.Token_exp{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	c.token = token.Token
+
+	return c.token, nil
+}
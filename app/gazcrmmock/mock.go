@@ -0,0 +1,91 @@
+package gazcrmmock
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
+)
+
+//Config mock behaviour
+type Config struct {
+	Addr        string        //listen address
+	Latency     time.Duration //min response latency
+	Jitter      time.Duration //random extra latency
+	FailureRate float64       //share of requests answered with 500, 0..1
+	RejectRate  float64       //share of requests answered with status Error, 0..1
+	Message     string        //message of accepted requests
+	Reject      string        //message of rejected requests
+	Callback    *Callback     //lifecycle callbacks, nil - disabled
+}
+
+//Mock gaz crm api
+type Mock struct {
+	config   Config
+	received uint64
+}
+
+//New mock
+func New(config Config) *Mock {
+	return &Mock{
+		config: config,
+	}
+}
+
+//Start listen and serve
+func (m *Mock) Start() error {
+	log.Printf("gazcrm mock listening on %s", m.config.Addr)
+	return http.ListenAndServe(m.config.Addr, m)
+}
+
+//ServeHTTP crm endpoint, accepts data of RequestGazCrmApiBooking/Forms on any path
+func (m *Mock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	n := atomic.AddUint64(&m.received, 1)
+
+	req := model.DataGazCrm{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, http.StatusBadRequest, &model.ResponseGazCrm{Status: "Error", Message: err.Error()})
+		return
+	}
+	requestId := req.Data.RequestId.RequestId
+
+	delay := m.config.Latency
+	if m.config.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(m.config.Jitter)))
+	}
+	time.Sleep(delay)
+
+	switch p := rand.Float64(); {
+	case p < m.config.FailureRate:
+		log.Printf("#%d %s: failure", n, requestId)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case p < m.config.FailureRate+m.config.RejectRate:
+		log.Printf("#%d %s: rejected", n, requestId)
+		respond(w, http.StatusOK, &model.ResponseGazCrm{Status: "Error", Message: m.config.Reject})
+		return
+	}
+
+	log.Printf("#%d %s: accepted (test=%t)", n, requestId, req.Data.TestMod.TestMod)
+	respond(w, http.StatusOK, &model.ResponseGazCrm{Status: "OK", Message: m.config.Message})
+
+	if m.config.Callback != nil && requestId != "" {
+		go m.config.Callback.Lifecycle(req.Data)
+	}
+}
+
+func respond(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
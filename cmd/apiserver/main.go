@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver"
 	"github.com/webdevolegkuprianov/server_http_rest/app/apiserver/model"
 
@@ -14,6 +17,30 @@ func main() {
 		logger.ErrorLogger.Println(err)
 	}
 
+	//apiserver migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		command := "status"
+		if len(os.Args) > 2 {
+			command = os.Args[2]
+		}
+		if err := apiserver.Migrate(config, command, os.Stdout); err != nil {
+			logger.ErrorLogger.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	//apiserver users list|create|disable|enable|delete|password|role|unlock, passwords on stdin
+	if len(os.Args) > 1 && os.Args[1] == "users" {
+		if err := apiserver.Users(config, os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			logger.ErrorLogger.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := apiserver.Start(config); err != nil {
 		logger.ErrorLogger.Println(err)
 	}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/webdevolegkuprianov/server_http_rest/app/gazcrmmock"
+)
+
+func main() {
+
+	config := gazcrmmock.Config{}
+
+	flag.StringVar(&config.Addr, "addr", ":8090", "listen address")
+	flag.DurationVar(&config.Latency, "latency", 100*time.Millisecond, "response latency")
+	flag.DurationVar(&config.Jitter, "jitter", 0, "random extra latency")
+	flag.Float64Var(&config.FailureRate, "failure-rate", 0, "share of 500 responses, 0..1")
+	flag.Float64Var(&config.RejectRate, "reject-rate", 0, "share of status Error responses, 0..1")
+	flag.StringVar(&config.Message, "message", "lead accepted", "message of accepted requests")
+	flag.StringVar(&config.Reject, "reject-message", "lead rejected", "message of rejected requests")
+
+	callback := &gazcrmmock.Callback{}
+	var statuses string
+	var insecure bool
+
+	flag.StringVar(&callback.Url, "callback-url", "", "server base url for lifecycle callbacks, empty - disabled")
+	flag.DurationVar(&callback.Step, "callback-step", time.Second, "delay between lifecycle events")
+	flag.StringVar(&statuses, "callback-statuses", "status_in_progress,status_won", "status event names, comma separated")
+	flag.StringVar(&callback.Email, "callback-email", "", "server user email")
+	flag.StringVar(&callback.Password, "callback-password", "", "server user password")
+	flag.StringVar(&callback.Integration, "webhook-integration", "gazcrm", "webhook integration name")
+	flag.StringVar(&callback.Secret, "webhook-secret", "", "webhook secret, signed /webhook routes instead of /auth")
+	flag.BoolVar(&insecure, "insecure", false, "skip server certificate verification")
+	flag.Parse()
+
+	if callback.Url != "" {
+		callback.Statuses = strings.Split(statuses, ",")
+		callback.Client = &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+			},
+		}
+		config.Callback = callback
+	}
+
+	if err := gazcrmmock.New(config).Start(); err != nil {
+		log.Fatal(err)
+	}
+
+}